@@ -0,0 +1,193 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/ryanl/vizid/pkg/vizid"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	checkAll  bool
+	checkJSON bool
+)
+
+var alphabetCmd = &cobra.Command{
+	Use:   "alphabet",
+	Short: "Inspect the registered glyph alphabets",
+}
+
+var alphabetListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List registered alphabets with their core and prefix glyphs",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		for _, a := range vizid.Alphabets() {
+			prefix := make([]rune, 0, len(vizid.PrefixSet))
+			for i := 0; i < len(vizid.PrefixSet); i++ {
+				g, _ := a.PrefixToGlyph(vizid.PrefixSet[i])
+				prefix = append(prefix, g)
+			}
+			fmt.Printf("%-16s %s  %s\n", a.ID(), string(a.Core()), string(prefix))
+		}
+		return nil
+	},
+}
+
+var alphabetCheckCmd = &cobra.Command{
+	Use:   "check [alphabet...]",
+	Short: "Audit glyph tables against the rules in docs/requirements.md",
+	Long: "Audit glyph tables against the rules in docs/requirements.md and print a\n" +
+		"pass/fail report per rule. With no arguments the selected --alphabet is\n" +
+		"checked; --all checks every registered alphabet. A glyphs: config section\n" +
+		"that fails to load is still audited from its raw entries.\n" +
+		"Exits non-zero if any rule is violated.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		reports, err := lintTargets(args)
+		if err != nil {
+			return err
+		}
+
+		failed := 0
+		for _, r := range reports {
+			if !r.Pass && !r.Legacy {
+				failed++
+			}
+		}
+
+		if checkJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(reports); err != nil {
+				return err
+			}
+		} else {
+			for _, r := range reports {
+				printLintReport(r)
+			}
+		}
+		if failed > 0 {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("alphabet check: %d of %d alphabet(s) failed", failed, len(reports))
+		}
+		return nil
+	},
+}
+
+func lintTargets(args []string) ([]vizid.LintReport, error) {
+	var reports []vizid.LintReport
+	rawName := ""
+	if configGlyphsErr != nil {
+		rawName = viper.GetString("glyphs.name")
+		if rawName == "" {
+			rawName = "custom"
+		}
+	}
+
+	switch {
+	case checkAll:
+		for _, a := range vizid.Alphabets() {
+			r := vizid.Lint(a)
+			r.Legacy = superseded(a)
+			reports = append(reports, r)
+		}
+		if rawName != "" {
+			reports = append(reports, lintConfigGlyphs(rawName))
+		}
+	case len(args) == 0:
+		if rawName != "" && viper.GetString("alphabet") == rawName {
+			return []vizid.LintReport{lintConfigGlyphs(rawName)}, nil
+		}
+		a, err := selectedAlphabet()
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, vizid.Lint(a))
+	default:
+		for _, name := range args {
+			if rawName != "" && name == rawName {
+				reports = append(reports, lintConfigGlyphs(rawName))
+				continue
+			}
+			a, err := vizid.LookupAlphabet(name)
+			if err != nil {
+				return nil, err
+			}
+			reports = append(reports, vizid.Lint(a))
+		}
+	}
+	return reports, nil
+}
+
+// superseded reports whether a newer version of a's alphabet is registered.
+func superseded(a *vizid.Alphabet) bool {
+	latest, err := vizid.LookupAlphabet(a.Name)
+	return err == nil && latest.Version > a.Version
+}
+
+// lintConfigGlyphs audits the raw `glyphs:` config entries. Every rune of
+// every entry is kept, so stray variation selectors or combining marks in a
+// multi-rune entry show up as violations.
+func lintConfigGlyphs(name string) vizid.LintReport {
+	flatten := func(entries []string) []rune {
+		var out []rune
+		for _, e := range entries {
+			out = append(out, []rune(e)...)
+		}
+		return out
+	}
+	return vizid.LintTables(name+" (config)",
+		flatten(viper.GetStringSlice("glyphs.core")),
+		flatten(viper.GetStringSlice("glyphs.prefix")))
+}
+
+func printLintReport(r vizid.LintReport) {
+	status := "PASS"
+	switch {
+	case r.Pass && waived(r):
+		status = "PASS (with waived rules)"
+	case !r.Pass && r.Legacy:
+		status = "FAIL (legacy, not counted)"
+	case !r.Pass:
+		status = "FAIL"
+	}
+	fmt.Printf("%s  %s\n", r.Alphabet, status)
+	for _, rule := range r.Rules {
+		mark := "ok  "
+		switch {
+		case !rule.Pass && rule.Waived != "":
+			mark = "WAIV"
+		case !rule.Pass:
+			mark = "FAIL"
+		}
+		fmt.Printf("  %s %-14s %s\n", mark, rule.Rule, rule.Description)
+		if rule.Waived != "" {
+			fmt.Printf("         waived: %s\n", rule.Waived)
+		}
+		for _, v := range rule.Violations {
+			fmt.Printf("         - %s\n", v)
+		}
+	}
+}
+
+// waived reports whether r has a failing rule its alphabet waives.
+func waived(r vizid.LintReport) bool {
+	for _, rule := range r.Rules {
+		if !rule.Pass && rule.Waived != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	rootCmd.AddCommand(alphabetCmd)
+	alphabetCmd.AddCommand(alphabetListCmd)
+	alphabetCmd.AddCommand(alphabetCheckCmd)
+
+	alphabetCheckCmd.Flags().BoolVar(&checkAll, "all", false, "check every registered alphabet")
+	alphabetCheckCmd.Flags().BoolVar(&checkJSON, "json", false, "print the reports as JSON")
+}
@@ -1,41 +1,182 @@
 package commands
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"strings"
 
-	"github.com/ryanl/vizid/internal/codec"
+	"github.com/ryanl/vizid/pkg/vizid"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"golang.org/x/text/width"
 )
 
 var decodeCmd = &cobra.Command{
 	Use:   "decode <vizid>",
 	Short: "Decode a VIZID into its ASCII wire format",
-	Args:  cobra.ExactArgs(1),
+	Long: "Decode a VIZID into its ASCII wire format. The --alphabet alphabet is tried\n" +
+		"first; if the glyphs do not belong to it, the alphabet is detected from the\n" +
+		"registered ones.\n\n" +
+		"With --lenient, IDs mangled by chat apps, PDFs or emoji fonts are cleaned up\n" +
+		"first: invisible characters (U+FE0F, zero-width joiners, bidi marks) are\n" +
+		"dropped, the input is NFC-normalized and lookalikes such as ⬥ or – are read\n" +
+		"as the glyphs they stand for. What was changed is noted on stderr.",
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		ascii, err := codec.DecodeVIZToASCII(args[0])
+		alpha, err := selectedAlphabet()
 		if err != nil {
 			return err
 		}
-		fmt.Println(ascii)
-		return nil
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+		leap, err := leapSecondPolicy(cmd, decodeLeap)
+		if err != nil {
+			return err
+		}
+		var id vizid.ID
+		var notes []string
+		if viper.GetBool("lenient") {
+			id, notes, err = decodeLenient(cmd, args[0], alpha, leap)
+		} else {
+			id, err = decodeStrict(cmd, args[0], alpha, leap)
+		}
+		if err != nil {
+			return err
+		}
+		if err := p.id(id, outputASCII, "", "", notes); err != nil {
+			return err
+		}
+		return p.close()
 	},
 }
 
+// decodeStrict reads a VIZID in any registered alphabet, preferring alpha.
+// leap only decides whether the --lenient hint applies.
+func decodeStrict(cmd *cobra.Command, input string, alpha *vizid.Alphabet, leap vizid.LeapSecondPolicy) (vizid.ID, error) {
+	ascii, a, err := vizid.DecodeAny(input, alpha)
+	if err != nil {
+		showDecodeError(cmd, os.Stderr, input, err)
+		if _, _, lerr := vizid.ParseLenient(input, alpha, leap); lerr == nil {
+			fmt.Fprintln(os.Stderr, "hint: it decodes with --lenient")
+		}
+		return vizid.ID{}, err
+	}
+	return vizid.ParseWith(ascii, a)
+}
+
+// decodeLenient is decode --lenient. The notes on what was cleaned up are
+// returned for the ID's output; when decoding fails they go to stderr with
+// the error.
+func decodeLenient(cmd *cobra.Command, input string, alpha *vizid.Alphabet, leap vizid.LeapSecondPolicy) (vizid.ID, []string, error) {
+	id, notes, err := vizid.ParseLenient(input, alpha, leap)
+	if err != nil {
+		if viper.GetBool("warn") {
+			for _, n := range notes {
+				fmt.Fprintln(os.Stderr, "note:", n)
+			}
+		}
+		cleaned, _ := vizid.Normalize(input)
+		showDecodeError(cmd, os.Stderr, cleaned, err)
+		return vizid.ID{}, nil, err
+	}
+	return id, notes, nil
+}
+
+var (
+	lenient    bool
+	decodeLeap string
+	leapSecond string
+)
+
+// leapSecondPolicy is the config leap_second, or the command's own
+// --leap-second flag (value flag) when it is given. encode binds its flag
+// to the config key instead, so only one command can.
+func leapSecondPolicy(cmd *cobra.Command, flag string) (vizid.LeapSecondPolicy, error) {
+	name := viper.GetString("leap_second")
+	if cmd.Flags().Changed("leap-second") {
+		name = flag
+	}
+	return vizid.ParseLeapSecondPolicy(name)
+}
+
 var encodeCmd = &cobra.Command{
 	Use:   "encode <ascii>",
 	Short: "Encode an ASCII wire ID into VIZ glyph form",
-	Args:  cobra.ExactArgs(1),
+	Long: "Encode an ASCII wire ID into VIZ glyph form. The date must exist in the\n" +
+		"Gregorian calendar (20250229 and 20250431 are refused); second 60, a leap\n" +
+		"second, is handled per --leap-second.",
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		viz, err := codec.EncodeASCIIToVIZ(args[0])
+		alpha, err := selectedAlphabet()
+		if err != nil {
+			return err
+		}
+		leap, err := vizid.ParseLeapSecondPolicy(viper.GetString("leap_second"))
+		if err != nil {
+			return err
+		}
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+		id, err := vizid.ParseASCII(args[0], alpha, leap)
 		if err != nil {
+			showDecodeError(cmd, os.Stderr, args[0], err)
 			return err
 		}
-		fmt.Println(viz)
-		return nil
+		if err := p.id(id, outputVIZ, "", "", nil); err != nil {
+			return err
+		}
+		return p.close()
 	},
 }
 
+// showDecodeError prints input with a caret under the glyph a
+// *vizid.DecodeError points at. Other errors are left to the caller.
+func showDecodeError(cmd *cobra.Command, w io.Writer, input string, err error) {
+	var de *vizid.DecodeError
+	if !errors.As(err, &de) {
+		return
+	}
+	cmd.SilenceUsage = true
+	col := 0
+	for i, r := range []rune(input) {
+		if i == de.Index {
+			break
+		}
+		col += glyphWidth(r)
+	}
+	reason := de.Reason
+	if len(de.Suggest) > 0 {
+		reason += "; did you mean " + de.SuggestString() + "?"
+	}
+	fmt.Fprintf(w, "  %s\n  %s^ %s\n", input, strings.Repeat(" ", col), reason)
+}
+
+// glyphWidth is the number of terminal columns r is likely to take.
+func glyphWidth(r rune) int {
+	switch width.LookupRune(r).Kind() {
+	case width.EastAsianWide, width.EastAsianFullwidth:
+		return 2
+	}
+	return 1
+}
+
 func init() {
 	rootCmd.AddCommand(decodeCmd)
 	rootCmd.AddCommand(encodeCmd)
+
+	addOutputFlag(decodeCmd, outputASCII)
+	addOutputFlag(encodeCmd, outputVIZ)
+
+	decodeCmd.Flags().BoolVar(&lenient, "lenient", false, "drop invisible characters, normalize Unicode and read lookalike glyphs as canonical ones")
+	_ = viper.BindPFlag("lenient", decodeCmd.Flags().Lookup("lenient"))
+	decodeCmd.Flags().StringVar(&decodeLeap, "leap-second", "", "second 60 in ASCII input to --lenient: reject, clamp or carry (default config leap_second)")
+
+	encodeCmd.Flags().StringVar(&leapSecond, "leap-second", "reject", "second 60: reject it, clamp it to :59.999, or carry it into the next minute")
+	_ = viper.BindPFlag("leap_second", encodeCmd.Flags().Lookup("leap-second"))
 }
@@ -0,0 +1,193 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/ryanl/vizid/pkg/vizid"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	convertTo      string
+	convertLenient bool
+	convertLeap    string
+)
+
+var convertCmd = &cobra.Command{
+	Use:   "convert <id>... | -",
+	Short: "Convert IDs between ASCII and VIZ form, detecting each input's form",
+	Long: "Convert IDs between the ASCII wire form and VIZ glyph form. Each input's form\n" +
+		"is detected on its own: one starting with a digit is ASCII, anything else VIZ\n" +
+		"(in any registered alphabet). With --to auto, the default, ASCII becomes VIZ\n" +
+		"in the --alphabet alphabet and VIZ becomes ASCII.\n\n" +
+		"An argument of - reads newline-delimited IDs from stdin; blank lines are\n" +
+		"skipped. Results go to stdout, one per line or in the --output format. A bad\n" +
+		"input is reported on stderr and skipped, and convert exits non-zero once all\n" +
+		"inputs are done if any failed.",
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		alpha, err := selectedAlphabet()
+		if err != nil {
+			return err
+		}
+		switch convertTo {
+		case "auto", "viz", "ascii":
+		default:
+			return fmt.Errorf("convert: unknown target %q (want auto, viz or ascii)", convertTo)
+		}
+		leap, err := leapSecondPolicy(cmd, convertLeap)
+		if err != nil {
+			return err
+		}
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+		c := &converter{
+			alpha:   alpha,
+			leap:    leap,
+			lenient: convertLenient || viper.GetBool("lenient"),
+			p:       p,
+		}
+		for _, arg := range args {
+			if arg == "-" {
+				err = c.read(os.Stdin)
+			} else {
+				err = c.line(arg, arg)
+			}
+			if err != nil {
+				p.close()
+				return err
+			}
+		}
+		if err := p.close(); err != nil {
+			return err
+		}
+		if c.failed > 0 {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("convert: %d of %d input(s) failed", c.failed, c.total)
+		}
+		return nil
+	},
+}
+
+// converter converts inputs one at a time, counting failures instead of
+// stopping at them.
+type converter struct {
+	alpha   *vizid.Alphabet
+	leap    vizid.LeapSecondPolicy
+	lenient bool
+	p       *printer
+
+	total, failed int
+}
+
+// maxLine is the longest line read converts; no ID comes near it. A longer
+// line fails as an input of its own, so it cannot stop the batch.
+const maxLine = 64 * 1024
+
+// read converts each non-blank line of r.
+func (c *converter) read(r io.Reader) error {
+	br := bufio.NewReaderSize(r, maxLine)
+	for n := 1; ; n++ {
+		b, err := br.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			in := strings.ToValidUTF8(string(b[:32]), "") + "..."
+			for err == bufio.ErrBufferFull {
+				_, err = br.ReadSlice('\n')
+			}
+			c.total++
+			if err := c.fail(fmt.Sprintf("line %d: %s", n, in), in, fmt.Errorf("line longer than %d bytes", maxLine), nil); err != nil {
+				return err
+			}
+		} else if s := strings.TrimSpace(string(b)); s != "" {
+			if err := c.line(fmt.Sprintf("line %d: %s", n, s), s); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// line converts one input, printing the result or reporting the error
+// prefixed with label. Only a failure to write is returned.
+func (c *converter) line(label, in string) error {
+	c.total++
+	id, natural, notes, err := c.convert(in)
+	if err != nil {
+		return c.fail(label, in, err, notes)
+	}
+	return c.p.id(id, natural, in, label, notes)
+}
+
+// fail counts a failed input and reports err prefixed with label. Only a
+// failure to write is returned.
+func (c *converter) fail(label, in string, err error, notes []string) error {
+	c.failed++
+	if err := c.p.fail(in, label, err, notes); err != nil {
+		return err
+	}
+	c.p.out.Flush() // keep stdout and stderr in order on a terminal
+	fmt.Fprintf(os.Stderr, "%s: %v\n", label, err)
+	return nil
+}
+
+// convert parses in, in whichever form it is, and returns it with the form
+// --to asks for.
+func (c *converter) convert(in string) (vizid.ID, outputFormat, []string, error) {
+	fromASCII := asciiForm(in)
+	var (
+		id    vizid.ID
+		notes []string
+		err   error
+	)
+	switch {
+	case c.lenient:
+		id, notes, err = vizid.ParseLenient(in, c.alpha, c.leap)
+	case fromASCII:
+		id, err = vizid.ParseASCII(in, c.alpha, c.leap)
+	default:
+		id, err = vizid.ParseWith(in, c.alpha)
+	}
+	if err != nil {
+		return vizid.ID{}, "", notes, err
+	}
+	if convertTo == "ascii" || convertTo == "auto" && !fromASCII {
+		return id, outputASCII, notes, nil
+	}
+	return id.WithAlphabet(c.alpha), outputVIZ, notes, nil
+}
+
+// asciiForm reports whether in looks like an ASCII wire ID: every one starts
+// with a year digit, and alphabets may not use ASCII digits as glyphs.
+// Fullwidth digits count too, so lenient input pasted from CJK text is read
+// as ASCII.
+func asciiForm(in string) bool {
+	in = strings.TrimLeftFunc(in, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.Is(unicode.Cf, r)
+	})
+	for _, r := range in {
+		return r >= '0' && r <= '9' || r >= '０' && r <= '９'
+	}
+	return false
+}
+
+func init() {
+	rootCmd.AddCommand(convertCmd)
+
+	convertCmd.Flags().StringVar(&convertTo, "to", "auto", "target form: auto (the other one), viz or ascii; --output overrides it")
+	addOutputFlag(convertCmd, "the --to form")
+	convertCmd.Flags().BoolVar(&convertLenient, "lenient", false, "decode leniently, as decode --lenient does (also set by config lenient)")
+	convertCmd.Flags().StringVar(&convertLeap, "leap-second", "", "second 60 in ASCII input: reject, clamp or carry (default config leap_second)")
+}
@@ -0,0 +1,97 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ryanl/vizid/pkg/vizid"
+	"github.com/spf13/pflag"
+)
+
+// execute runs the vizid command line args with stdin as standard input and
+// no config file, returning what it wrote to stdout and stderr. Flags start
+// from their defaults, whatever an earlier run set.
+func execute(t *testing.T, stdin string, args ...string) (stdout, stderr string, err error) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	reset := func(f *pflag.Flag) {
+		_ = f.Value.Set(f.DefValue)
+		f.Changed = false
+	}
+	rootCmd.PersistentFlags().VisitAll(reset)
+	if cmd, _, err := rootCmd.Find(args); err == nil {
+		cmd.Flags().VisitAll(reset)
+	}
+
+	files := make([]*os.File, 3)
+	for i, name := range []string{"stdin", "stdout", "stderr"} {
+		p := filepath.Join(dir, name)
+		if i == 0 {
+			if err := os.WriteFile(p, []byte(stdin), 0o600); err != nil {
+				t.Fatal(err)
+			}
+		}
+		f, err := os.OpenFile(p, os.O_RDWR|os.O_CREATE, 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		files[i] = f
+	}
+	saved := []*os.File{os.Stdin, os.Stdout, os.Stderr}
+	os.Stdin, os.Stdout, os.Stderr = files[0], files[1], files[2]
+	rootCmd.SetArgs(args)
+	err = rootCmd.Execute()
+	os.Stdin, os.Stdout, os.Stderr = saved[0], saved[1], saved[2]
+
+	out, rerr := os.ReadFile(files[1].Name())
+	if rerr != nil {
+		t.Fatal(rerr)
+	}
+	errOut, rerr := os.ReadFile(files[2].Name())
+	if rerr != nil {
+		t.Fatal(rerr)
+	}
+	return string(out), string(errOut), err
+}
+
+func TestConvertPartialFailure(t *testing.T) {
+	const ascii = "20240229120000000-%GT007"
+	viz, err := vizid.Encode(ascii, vizid.DefaultAlphabet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name  string
+		stdin string
+		args  []string
+	}{
+		{"args", "", []string{"convert", ascii, "bogus", viz}},
+		{"stdin", ascii + "\nbogus\n\n" + viz + "\n", []string{"convert", "-"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			stdout, stderr, err := execute(t, tc.stdin, tc.args...)
+			if err == nil || !strings.Contains(err.Error(), "1 of 3 input(s) failed") {
+				t.Errorf("err = %v, want 1 of 3 inputs failed", err)
+			}
+			if want := viz + "\n" + ascii + "\n"; stdout != want {
+				t.Errorf("stdout = %q, want the good inputs converted: %q", stdout, want)
+			}
+			if !strings.Contains(stderr, "bogus") {
+				t.Errorf("stderr = %q, want the bad input reported", stderr)
+			}
+			if strings.Contains(stderr, "Usage:") {
+				t.Errorf("stderr = %q, want no usage text for a bad input", stderr)
+			}
+		})
+	}
+
+	stdout, _, err := execute(t, "", "convert", ascii, viz)
+	if err != nil || stdout != viz+"\n"+ascii+"\n" {
+		t.Errorf("all good inputs: stdout = %q, err = %v; want both converted and no error", stdout, err)
+	}
+}
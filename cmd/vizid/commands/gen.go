@@ -2,30 +2,64 @@ package commands
 
 import (
 	"fmt"
+	"os"
+	"time"
 
-	"github.com/ryanl/vizid/internal/generator"
-	"github.com/ryanl/vizid/internal/model"
+	"github.com/ryanl/vizid/pkg/vizid"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 var (
-	compYear   bool
-	compMonth  bool
-	compDay    bool
-	compHour   bool
-	compMinute bool
-	compSecond bool
-	compMs     bool
-	compUUID   bool
+	compYear    bool
+	compMonth   bool
+	compDay     bool
+	compHour    bool
+	compMinute  bool
+	compSecond  bool
+	compMs      bool
+	compUUID    bool
 	userDefined bool
+	clockPolicy string
+	overflow    string
+	maxDrift    time.Duration
+	genCount    int
+	genASCII    bool
+	genAt       string
+	genContent  string
+	genNS       string
+	genMixer    string
+	genLayout   string
+	persist     bool
+	stateFile   string
 )
 
 var genCmd = &cobra.Command{
 	Use:   "gen",
-	Short: "Generate a new VIZID (visual form, suitable for filenames)",
+	Short: "Generate new VIZIDs (visual form, suitable for filenames)",
+	Long: "Generate new VIZIDs (visual form, suitable for filenames).\n\n" +
+		"UUID layouts (--layout, config `layout`):\n\n" +
+		"  classic    PTTCCR: node prefix, time-mix, 2 counter digits, node salt (default);\n" +
+		"             with --mixer keyed, PTTCCRM, where M records the mixer\n" +
+		"  tt+c2+r1   extended: any of tt (2-digit time-mix), cN (N counter digits, 1-3,\n" +
+		"             default 2) and rN (N random digits, 0-3, default 0)\n\n" +
+		"An extended UUID is ^D followed by the parts, where D records the layout and\n" +
+		"mixer so decode can split it. Random digits come from crypto/rand. A layout\n" +
+		"with cN allows 36^N IDs per millisecond.\n\n" +
+		"Collisions: one generator never repeats an ID. Two generators collide only on\n" +
+		"the same millisecond and counter value, and then only if their random digits\n" +
+		"(and, with --mixer keyed, their time-mix) also match. For k generators on the\n" +
+		"same millisecond and counter the chance of a collision is about k(k-1)/2N:\n\n" +
+		"  random digits   N (v1 or no time-mix)   N (keyed time-mix)\n" +
+		"  r0              1                       1296\n" +
+		"  r1              36                      46656\n" +
+		"  r2              1296                    1679616\n" +
+		"  r3              46656                   60466176\n\n" +
+		"e.g. 10 hosts with tt+c2+r3 and --mixer keyed: 45/60466176, about 7e-7.\n" +
+		"The classic layout relies on nodes instead: distinct fixed nodes never collide,\n" +
+		"random nodes pick the same one with probability 1/216 per pair.",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		components := model.Components{
+		components := vizid.Components{
 			Year:   viper.GetBool("components.year"),
 			Month:  viper.GetBool("components.month"),
 			Day:    viper.GetBool("components.day"),
@@ -37,7 +71,7 @@ var genCmd = &cobra.Command{
 		}
 
 		if userDefined {
-			components = model.Components{}
+			components = vizid.Components{}
 			if cmd.Flags().Changed("year") {
 				components.Year = compYear
 			}
@@ -89,25 +123,157 @@ var genCmd = &cobra.Command{
 			}
 		}
 
-		opts := model.Options{
-			Timezone: viper.GetString("timezone"),
-			Warn:     viper.GetBool("warn"),
-			Custom:   viper.GetBool("custom") || userDefined,
-			Components: components,
+		opts := vizid.Options{
+			Timezone:    viper.GetString("timezone"),
+			Warn:        viper.GetBool("warn"),
+			Custom:      viper.GetBool("custom") || userDefined,
+			ClockPolicy: vizid.ClockPolicy(viper.GetString("clock_policy")),
+			Overflow:    vizid.OverflowPolicy(viper.GetString("overflow")),
+			MaxDrift:    viper.GetDuration("max_drift"),
+			Components:  components,
 		}
 
-		id, _, warnMsg, err := generator.Generate(opts)
+		alpha, err := selectedAlphabet()
 		if err != nil {
 			return err
 		}
-		if opts.Warn && warnMsg != "" {
-			fmt.Println("WARN:", warnMsg)
+		genOpts, err := stateFileOptions()
+		if err != nil {
+			return err
+		}
+		if n, ok, err := configuredNode(); err != nil {
+			return err
+		} else if ok {
+			// --at counters are per process unless a state file keeps
+			// them, and a fixed node would then repeat the last run's IDs
+			if genAt != "" && genContent == "" && len(genOpts) == 0 {
+				return fmt.Errorf("--at with a fixed node (%s) needs --persist or --state-file, or a later run would repeat these IDs", n)
+			}
+			genOpts = append(genOpts, vizid.WithNode(n))
+		}
+		mixer, err := vizid.ParseMixer(viper.GetString("mixer"), []byte(viper.GetString("mixer_secret")))
+		if err != nil {
+			return err
+		}
+		layout, err := vizid.ParseLayout(viper.GetString("layout"))
+		if err != nil {
+			return err
+		}
+		genOpts = append(genOpts, vizid.WithMixer(mixer), vizid.WithLayout(layout))
+		if genCount < 1 {
+			return fmt.Errorf("--count must be at least 1, got %d", genCount)
+		}
+		p, err := newPrinter(cmd)
+		if err != nil {
+			return err
+		}
+		natural := outputVIZ
+		if genASCII {
+			if p.format != "" && p.format != outputASCII {
+				return fmt.Errorf("--ascii conflicts with --output %s", p.format)
+			}
+			natural = outputASCII
+		}
+		if genContent != "" {
+			return genFromContent(cmd, p, natural, opts, alpha)
+		}
+		var block vizid.Block
+		var warnMsg string
+		if genAt != "" {
+			loc, err := vizid.LoadLocation(opts.Timezone)
+			if err != nil {
+				return err
+			}
+			at, err := vizid.ParseTime(genAt, loc, time.Now())
+			if err != nil {
+				return err
+			}
+			block, warnMsg, err = vizid.GenerateAt(cmd.Context(), opts, alpha, at, genCount, genOpts...)
+			if err != nil {
+				return err
+			}
+		} else {
+			block, warnMsg, err = vizid.GenerateN(cmd.Context(), opts, alpha, genCount, genOpts...)
+			if err != nil {
+				return err
+			}
 		}
-		fmt.Println(id)
-		return nil
+		if warnMsg != "" {
+			p.warn(warnMsg)
+		}
+		for i := 0; i < block.Len(); i++ {
+			id, err := block.ID(i)
+			if err != nil {
+				return err
+			}
+			if err := p.id(id, natural, "", "", nil); err != nil {
+				return err
+			}
+		}
+		return p.close()
 	},
 }
 
+// genFromContent prints the content-derived ID of the --from-content file
+// (or stdin for "-"), stamped with --at or else the file's modification time.
+func genFromContent(cmd *cobra.Command, p *printer, natural outputFormat, opts vizid.Options, alpha *vizid.Alphabet) error {
+	if genCount != 1 {
+		return fmt.Errorf("--from-content derives exactly one ID; --count cannot be used with it")
+	}
+	loc, err := vizid.LoadLocation(opts.Timezone)
+	if err != nil {
+		return err
+	}
+	var at time.Time
+	if genAt != "" {
+		if at, err = vizid.ParseTime(genAt, loc, time.Now()); err != nil {
+			return err
+		}
+	}
+	content := os.Stdin
+	if genContent != "-" {
+		f, err := os.Open(genContent)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if genAt == "" {
+			info, err := f.Stat()
+			if err != nil {
+				return err
+			}
+			at = info.ModTime()
+		}
+		content = f
+	} else if genAt == "" {
+		return fmt.Errorf("--from-content - reads stdin, which has no modification time; give --at")
+	}
+	id, err := vizid.FromContent(opts, alpha, at, genNS, content)
+	if err != nil {
+		return err
+	}
+	if err := p.id(id, natural, "", "", nil); err != nil {
+		return err
+	}
+	return p.close()
+}
+
+// stateFileOptions returns the generator option for the persistent state
+// file, if `persist` is on or a `state_file` is given.
+func stateFileOptions() ([]vizid.GeneratorOption, error) {
+	path := viper.GetString("state_file")
+	if !viper.GetBool("persist") && path == "" {
+		return nil, nil
+	}
+	if path == "" {
+		var err error
+		if path, err = vizid.DefaultStatePath(); err != nil {
+			return nil, err
+		}
+	}
+	return []vizid.GeneratorOption{vizid.WithStateFile(path)}, nil
+}
+
 func init() {
 	rootCmd.AddCommand(genCmd)
 
@@ -121,5 +287,27 @@ func init() {
 	genCmd.Flags().BoolVar(&compMs, "ms", true, "include milliseconds")
 	genCmd.Flags().BoolVar(&compUUID, "uuid", true, "include uuid")
 
+	genCmd.Flags().IntVarP(&genCount, "count", "n", 1, "number of IDs to generate, one per line, strictly increasing")
+	genCmd.Flags().StringVar(&genAt, "at", "", "mint IDs for this past instant instead of now, e.g. 2024-02-29T10:00:00Z, 20240229100000500, ms:1709200800500, -2h or 'yesterday 15:30' (see docs/cli.md)")
+	genCmd.Flags().StringVar(&genContent, "from-content", "", "derive the ID from a file's content (- for stdin): same content, namespace and time give the same ID")
+	genCmd.Flags().StringVar(&genNS, "namespace", "", "namespace mixed into the --from-content hash, to keep separate ID spaces apart")
+	genCmd.Flags().BoolVar(&genASCII, "ascii", false, "print the ASCII wire form instead of the visual form (same as --output ascii)")
+	addOutputFlag(genCmd, outputVIZ)
+	genCmd.Flags().StringVar(&clockPolicy, "clock-policy", "error", "when the clock steps backwards: error, wait, logical (count on from the last ID) or warn")
+
 	_ = viper.BindPFlag("custom", genCmd.Flags().Lookup("user-defined"))
+	genCmd.Flags().StringVar(&overflow, "overflow", "wait", "when a millisecond's 1296 counter values run out: wait for the next one, or borrow it without waiting")
+	genCmd.Flags().DurationVar(&maxDrift, "max-drift", time.Second, "how far borrowing may run ahead of the clock (0 for no limit)")
+	genCmd.Flags().StringVar(&genMixer, "mixer", "v1", "time-mix function for TT: v1, or keyed (hashes the full timestamp and node with the mixer_secret config key)")
+	genCmd.Flags().StringVar(&genLayout, "layout", "classic", "UUID layout: classic, or extended parts tt, cN, rN joined by + (e.g. tt+c2+r1; see --help)")
+	genCmd.Flags().BoolVar(&persist, "persist", false, "keep the counter in a locked state file so separate runs stay strictly monotonic")
+	genCmd.Flags().StringVar(&stateFile, "state-file", "", "state file path, implies --persist (default ~/.local/state/vizid/state)")
+
+	_ = viper.BindPFlag("clock_policy", genCmd.Flags().Lookup("clock-policy"))
+	_ = viper.BindPFlag("overflow", genCmd.Flags().Lookup("overflow"))
+	_ = viper.BindPFlag("max_drift", genCmd.Flags().Lookup("max-drift"))
+	_ = viper.BindPFlag("mixer", genCmd.Flags().Lookup("mixer"))
+	_ = viper.BindPFlag("layout", genCmd.Flags().Lookup("layout"))
+	_ = viper.BindPFlag("persist", genCmd.Flags().Lookup("persist"))
+	_ = viper.BindPFlag("state_file", genCmd.Flags().Lookup("state-file"))
 }
@@ -0,0 +1,104 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/ryanl/vizid/pkg/vizid"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	lsSince string
+	lsUntil string
+)
+
+var lsCmd = &cobra.Command{
+	Use:   "ls [dir]...",
+	Short: "List files named with a VIZID, in ID order, optionally by time range",
+	Long: "List the entries of each directory (default .) whose names start with a\n" +
+		"VIZID in any registered alphabet, sorted by ID. --since and --until take the\n" +
+		"same time expressions as gen --at, e.g. 'last monday', -2h or\n" +
+		"'2024-02-29 15:30'; ID timestamps are read as wall time in --timezone.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			args = []string{"."}
+		}
+		loc, err := vizid.LoadLocation(viper.GetString("timezone"))
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		var since, until time.Time
+		if lsSince != "" {
+			if since, err = vizid.ParseTime(lsSince, loc, now); err != nil {
+				return fmt.Errorf("--since: %w", err)
+			}
+		}
+		if lsUntil != "" {
+			if until, err = vizid.ParseTime(lsUntil, loc, now); err != nil {
+				return fmt.Errorf("--until: %w", err)
+			}
+		}
+		alpha, err := selectedAlphabet()
+		if err != nil {
+			return err
+		}
+
+		type entry struct {
+			path string
+			id   vizid.ID
+		}
+		var entries []entry
+		for _, dir := range args {
+			names, err := os.ReadDir(dir)
+			if err != nil {
+				return err
+			}
+			for _, e := range names {
+				id, ok := nameID(e.Name(), alpha)
+				if !ok {
+					continue
+				}
+				t := id.TimeIn(loc)
+				if (!since.IsZero() && t.Before(since)) || (!until.IsZero() && !t.Before(until)) {
+					continue
+				}
+				entries = append(entries, entry{filepath.Join(dir, e.Name()), id})
+			}
+		}
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].id.ASCII() < entries[j].id.ASCII()
+		})
+		for _, e := range entries {
+			fmt.Println(e.path)
+		}
+		return nil
+	},
+}
+
+// nameID parses the VIZID a file name starts with: a full ID, or a
+// timestamp-only one.
+func nameID(name string, alpha *vizid.Alphabet) (vizid.ID, bool) {
+	r := []rune(name)
+	for _, n := range append(vizLens[:len(vizLens):len(vizLens)], 12) {
+		if len(r) < n {
+			continue
+		}
+		if id, err := vizid.ParseWith(string(r[:n]), alpha); err == nil {
+			return id, true
+		}
+	}
+	return vizid.ID{}, false
+}
+
+func init() {
+	rootCmd.AddCommand(lsCmd)
+
+	lsCmd.Flags().StringVar(&lsSince, "since", "", "only IDs at or after this time")
+	lsCmd.Flags().StringVar(&lsUntil, "until", "", "only IDs before this time")
+}
@@ -0,0 +1,168 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ryanl/vizid/pkg/vizid"
+	"github.com/spf13/cobra"
+)
+
+// vizLens are the rune lengths of a full VIZID, longest first: 12 timestamp
+// glyphs, '-' and a UUID of MinUUIDWidth to MaxUUIDWidth glyphs. A prefix
+// and descriptor fix a UUID's width, so at most one of them parses.
+var vizLens = func() []int {
+	var ns []int
+	for w := vizid.MaxUUIDWidth; w >= vizid.MinUUIDWidth; w-- {
+		ns = append(ns, 12+1+w)
+	}
+	return ns
+}()
+
+var (
+	migrateDryRun bool
+	migrateFrom   string
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate <path>...",
+	Short: "Rename files from one glyph alphabet to another (default: legacy v1 to current)",
+	Long: "Rename files whose names start with a VIZID written in the --from alphabet\n" +
+		"(default geometric@1, the legacy v1 table). Each ID is decoded to its ASCII wire\n" +
+		"form and re-encoded with the --alphabet alphabet. Directories are migrated one\n" +
+		"level deep. Names that already decode in the --alphabet alphabet are skipped,\n" +
+		"so running it again is harmless; skipped and undecodable names are reported\n" +
+		"on stderr. Use --dry-run to preview the renames.",
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		from, err := vizid.LookupAlphabet(migrateFrom)
+		if err != nil {
+			return err
+		}
+		to, err := selectedAlphabet()
+		if err != nil {
+			return err
+		}
+		if from == to {
+			return fmt.Errorf("migrate: source and target alphabet are both %s", to.ID())
+		}
+		failed := 0
+		for _, arg := range args {
+			paths, err := migrateTargets(arg)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				failed++
+				continue
+			}
+			for _, p := range paths {
+				if err := migrateFile(p, from, to, migrateDryRun); err != nil {
+					fmt.Fprintln(os.Stderr, err)
+					failed++
+				}
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("migrate: %d path(s) failed", failed)
+		}
+		return nil
+	},
+}
+
+func migrateTargets(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, 0, len(entries))
+	for _, e := range entries {
+		paths = append(paths, filepath.Join(path, e.Name()))
+	}
+	return paths, nil
+}
+
+// migrateFile renames path if its base name starts with a VIZID in the from
+// alphabet. Names that already start with a VIZID in the to alphabet are
+// left alone, so a second run changes nothing; they and names that do not
+// decode are reported on stderr.
+func migrateFile(path string, from, to *vizid.Alphabet, dryRun bool) error {
+	name := []rune(filepath.Base(path))
+	for _, l := range vizLens {
+		if len(name) >= l {
+			if _, err := vizid.Decode(string(name[:l]), to); err == nil {
+				fmt.Fprintf(os.Stderr, "skip %s: already a %s ID\n", path, to.ID())
+				return nil
+			}
+		}
+	}
+	n, newID := 0, ""
+	var why error
+	for _, l := range vizLens {
+		if len(name) < l {
+			continue
+		}
+		id, err := vizid.Reencode(string(name[:l]), from, to)
+		if err == nil {
+			n, newID = l, id
+			break
+		}
+		if why == nil || decodeErrorAt(err) > decodeErrorAt(why) {
+			why = err
+		}
+	}
+	if n == 0 {
+		if why == nil {
+			fmt.Fprintf(os.Stderr, "skip %s: too short for a VIZID\n", path)
+		} else {
+			fmt.Fprintf(os.Stderr, "skip %s: not a %s ID: %v\n", path, from.ID(), why)
+		}
+		return nil
+	}
+	target := filepath.Join(filepath.Dir(path), newID+string(name[n:]))
+	fmt.Printf("%s -> %s\n", path, target)
+	if !readsAlike(string(name[:n]), from, newID, to) {
+		fmt.Fprintf(os.Stderr, "note %s: its 1.x UUID reads as an extended one in %s; the ASCII form and order are unchanged\n", path, to.ID())
+	}
+	if dryRun {
+		return nil
+	}
+	if _, err := os.Lstat(target); err == nil {
+		return fmt.Errorf("migrate %s: target already exists: %s", path, target)
+	}
+	return os.Rename(path, target)
+}
+
+// readsAlike reports whether old, a VIZID in alphabet from, and its
+// re-encoding new in alphabet to have the same kind of UUID. A 1.x ID with
+// prefix ^ whose second digit names a 6-wide layout does not: 1.x read it
+// as a classic PTTCCR, later alphabets as an extended UUID.
+func readsAlike(old string, from *vizid.Alphabet, new string, to *vizid.Alphabet) bool {
+	a, errA := vizid.ParseWith(old, from)
+	b, errB := vizid.ParseWith(new, to)
+	return errA != nil || errB != nil || a.IsExtended() == b.IsExtended()
+}
+
+// decodeErrorAt is how far into the input a decode error occurred, so the
+// candidate length that got furthest explains why a name was skipped.
+func decodeErrorAt(err error) int {
+	var de *vizid.DecodeError
+	if errors.As(err, &de) {
+		return de.Index
+	}
+	return -1
+}
+
+func init() {
+	rootCmd.AddCommand(migrateCmd)
+
+	migrateCmd.Flags().StringVar(&migrateFrom, "from", vizid.LegacyAlphabet.ID(), "alphabet the existing filenames are written in")
+	migrateCmd.Flags().BoolVarP(&migrateDryRun, "dry-run", "n", false, "print the renames without performing them")
+}
@@ -0,0 +1,87 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/ryanl/vizid/pkg/vizid"
+)
+
+func TestMigrateIdempotent(t *testing.T) {
+	dir := t.TempDir()
+	asciis := []string{"20240229120000000-%GT007", "20240229120000001-^1A2B3", "20240229120000002-~GT007"}
+	for _, ascii := range asciis {
+		viz, err := vizid.Encode(ascii, vizid.LegacyAlphabet)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, viz+" notes.txt"), nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	migrate := func(dryRun bool) []string {
+		t.Helper()
+		paths, err := migrateTargets(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range paths {
+			if err := migrateFile(p, vizid.LegacyAlphabet, vizid.DefaultAlphabet, dryRun); err != nil {
+				t.Fatal(err)
+			}
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	before := migrate(true)
+	if got := migrate(true); !equalStrings(got, before) {
+		t.Fatalf("a dry run renamed files: %q -> %q", before, got)
+	}
+	first := migrate(false)
+	if second := migrate(false); !equalStrings(second, first) {
+		t.Errorf("a second run renamed files: %q -> %q", first, second)
+	}
+
+	var got []string
+	for _, name := range first {
+		if name == "README" {
+			continue
+		}
+		r := []rune(name)
+		id, err := vizid.ParseWith(string(r[:len(r)-len(" notes.txt")]), vizid.DefaultAlphabet)
+		if err != nil || id.Alphabet() != vizid.DefaultAlphabet {
+			t.Fatalf("%s: not a %s ID: %v", name, vizid.DefaultAlphabet.ID(), err)
+		}
+		got = append(got, id.ASCII())
+	}
+	if !equalStrings(got, asciis) {
+		t.Errorf("migrated IDs %q, want %q in the same order", got, asciis)
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
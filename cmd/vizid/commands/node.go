@@ -0,0 +1,152 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ryanl/vizid/pkg/vizid"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+var nodeCmd = &cobra.Command{
+	Use:   "node",
+	Short: "Show the node identity (UUID prefix + salt) that gen uses",
+	Long: "A node is the UUID prefix P and salt digit R of generated IDs: one of\n" +
+		fmt.Sprintf("%q", vizid.NodePrefixes) + " and a base-36 digit, written like \"%7\", for 216 nodes in all.\n" +
+		fmt.Sprintf("The prefixes %q and %q are reserved for extended UUIDs (gen --layout)\n", vizid.DescriptorPrefix, vizid.ContentPrefix) +
+		"and content-derived IDs (gen --from-content).\n" +
+		"The `node_id` config key selects it:\n\n" +
+		"  random (or unset)  a new node is drawn for every process\n" +
+		"  host               derived from the hostname and machine id\n" +
+		"  %7                 a fixed node; give each machine of a fleet its own\n",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		spec := viper.GetString("node_id")
+		n, ok, err := configuredNode()
+		if err != nil {
+			return err
+		}
+		switch {
+		case !ok:
+			fmt.Println("random (a new node for each process; pin one with 'vizid node set')")
+		case strings.EqualFold(spec, "host"):
+			fmt.Printf("%s (host fingerprint)\n", n)
+		default:
+			fmt.Println(n)
+		}
+		return nil
+	},
+}
+
+var nodeSetCmd = &cobra.Command{
+	Use:   "set <node|host|random>",
+	Short: "Write node_id to the config file",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		spec := args[0]
+		switch strings.ToLower(spec) {
+		case "host", "random":
+			spec = strings.ToLower(spec)
+		default:
+			n, err := vizid.ParseNode(spec)
+			if err != nil {
+				return err
+			}
+			spec = n.String()
+		}
+		path, err := configPath()
+		if err != nil {
+			return err
+		}
+		if err := setConfigKey(path, "node_id", spec); err != nil {
+			return err
+		}
+		fmt.Printf("node_id: %s (%s)\n", spec, path)
+		return nil
+	},
+}
+
+// configuredNode resolves the `node_id` config key. ok is false when the
+// node should be drawn at random.
+func configuredNode() (n vizid.Node, ok bool, err error) {
+	spec := strings.TrimSpace(viper.GetString("node_id"))
+	switch strings.ToLower(spec) {
+	case "", "random":
+		return vizid.Node{}, false, nil
+	case "host":
+		n, err = vizid.HostNode()
+	default:
+		n, err = vizid.ParseNode(spec)
+	}
+	if err != nil {
+		return vizid.Node{}, false, fmt.Errorf("node_id: %w", err)
+	}
+	return n, true, nil
+}
+
+// configPath returns the config file in use, or the default location.
+func configPath() (string, error) {
+	if p := viper.ConfigFileUsed(); p != "" {
+		return p, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "vizid", "config.yaml"), nil
+}
+
+// setConfigKey sets a top-level string key in a YAML config file, keeping the
+// rest of the file (including comments) as it is. The file is created if
+// missing.
+func setConfigKey(path, key, value string) error {
+	var doc yaml.Node
+	b, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if len(bytes.TrimSpace(b)) > 0 {
+		if err := yaml.Unmarshal(b, &doc); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("%s: top level is not a mapping", path)
+	}
+	val := &yaml.Node{Kind: yaml.ScalarNode, Value: value, Style: yaml.DoubleQuotedStyle}
+	found := false
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == key {
+			root.Content[i+1] = val
+			found = true
+		}
+	}
+	if !found {
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, val)
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+func init() {
+	rootCmd.AddCommand(nodeCmd)
+	nodeCmd.AddCommand(nodeSetCmd)
+}
@@ -0,0 +1,299 @@
+package commands
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ryanl/vizid/pkg/vizid"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// outputFormat is how gen, decode, encode and convert print IDs.
+type outputFormat string
+
+const (
+	outputVIZ    outputFormat = "viz"    // the glyph form, one per line
+	outputASCII  outputFormat = "ascii"  // the wire form, one per line
+	outputBoth   outputFormat = "both"   // VIZ, a tab, then ASCII
+	outputJSON   outputFormat = "json"   // one document: {"ids": [...], "warnings": [...]}
+	outputNDJSON outputFormat = "ndjson" // one JSON object per ID
+	outputTSV    outputFormat = "tsv"    // a header row, then one row per ID
+)
+
+func parseOutputFormat(s string) (outputFormat, error) {
+	switch f := outputFormat(s); f {
+	case outputVIZ, outputASCII, outputBoth, outputJSON, outputNDJSON, outputTSV:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown output format %q (want viz, ascii, both, json, ndjson or tsv)", s)
+}
+
+// structured reports whether f carries warnings in its records instead of
+// leaving them to stderr.
+func (f outputFormat) structured() bool {
+	return f == outputJSON || f == outputNDJSON
+}
+
+// addOutputFlag gives cmd an --output flag. Commands share the `output`
+// config key, so the flag is read through selectedOutput rather than bound.
+func addOutputFlag(cmd *cobra.Command, natural outputFormat) {
+	cmd.Flags().StringP("output", "o", "", fmt.Sprintf("output format: viz, ascii, both, json, ndjson or tsv (default config output, else %s)", natural))
+}
+
+// selectedOutput resolves cmd's --output flag, then the `output` config key;
+// "" means the command's own default.
+func selectedOutput(cmd *cobra.Command) (outputFormat, error) {
+	s := viper.GetString("output")
+	if f := cmd.Flags().Lookup("output"); f != nil && f.Changed {
+		s = f.Value.String()
+	}
+	if s == "" {
+		return "", nil
+	}
+	return parseOutputFormat(s)
+}
+
+// idRecord is an ID in the json, ndjson and tsv formats.
+type idRecord struct {
+	Input      string            `json:"input,omitempty"`
+	VIZ        string            `json:"viz,omitempty"`
+	ASCII      string            `json:"ascii,omitempty"`
+	Alphabet   string            `json:"alphabet,omitempty"`
+	Time       string            `json:"time,omitempty"`
+	Timezone   string            `json:"timezone,omitempty"`
+	Components *componentsRecord `json:"components,omitempty"`
+	UUID       *uuidRecord       `json:"uuid,omitempty"`
+	Warnings   []string          `json:"warnings,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// componentsRecord holds the timestamp fields as stored; a component that
+// was disabled when the ID was made reads as zero.
+type componentsRecord struct {
+	Year   int `json:"year"`
+	Month  int `json:"month"`
+	Day    int `json:"day"`
+	Hour   int `json:"hour"`
+	Minute int `json:"minute"`
+	Second int `json:"second"`
+	Ms     int `json:"ms"`
+}
+
+// uuidRecord holds the UUID fields. Node is the classic node (prefix and
+// salt); extended UUIDs have Random instead, content-derived ones a
+// ContentHash.
+type uuidRecord struct {
+	Prefix      string `json:"prefix"`
+	Layout      string `json:"layout"`
+	Mixer       string `json:"mixer,omitempty"`
+	Node        string `json:"node,omitempty"`
+	TimeMix     int    `json:"time_mix"`
+	Counter     int    `json:"counter"`
+	Salt        int    `json:"salt"`
+	Random      int    `json:"random"`
+	ContentHash *int   `json:"content_hash,omitempty"`
+}
+
+// tsvHeader names the tsv columns.
+var tsvHeader = []string{"viz", "ascii", "alphabet", "time", "timezone", "prefix", "layout", "mixer", "time_mix", "counter", "salt", "random"}
+
+// rfc3339Milli is RFC 3339 with the millisecond an ID carries.
+const rfc3339Milli = "2006-01-02T15:04:05.000Z07:00"
+
+func newIDRecord(id vizid.ID, loc *time.Location) idRecord {
+	ts := id.Timestamp()
+	r := idRecord{
+		VIZ:      id.VIZ(),
+		ASCII:    id.ASCII(),
+		Alphabet: id.Alphabet().ID(),
+		Time:     id.TimeIn(loc).Format(rfc3339Milli),
+		Timezone: loc.String(),
+		Components: &componentsRecord{
+			Year: ts.Year, Month: ts.Month, Day: ts.Day,
+			Hour: ts.Hour, Minute: ts.Minute, Second: ts.Second, Ms: ts.Millis,
+		},
+	}
+	if !id.HasUUID() {
+		return r
+	}
+	u := &uuidRecord{
+		Prefix:  string(id.Prefix()),
+		Layout:  id.Layout().String(),
+		TimeMix: id.TimeMix(),
+		Counter: id.Counter(),
+		Salt:    id.Salt(),
+		Random:  id.Random(),
+	}
+	if id.Layout().TimeMix {
+		u.Mixer = id.Mixer().String()
+	}
+	if h, ok := id.ContentHash(); ok {
+		u.ContentHash = &h
+		u.Layout, u.Mixer = "content", ""
+	} else if !id.IsExtended() {
+		u.Node = vizid.Node{Prefix: id.Prefix(), Salt: id.Salt()}.String()
+	}
+	r.UUID = u
+	return r
+}
+
+// tsvRow renders r's tsv columns; a timestamp-only ID leaves the UUID
+// columns empty.
+func (r idRecord) tsvRow() []string {
+	row := []string{r.VIZ, r.ASCII, r.Alphabet, r.Time, r.Timezone}
+	if r.UUID == nil {
+		return append(row, make([]string, len(tsvHeader)-len(row))...)
+	}
+	u := r.UUID
+	return append(row, u.Prefix, u.Layout, u.Mixer,
+		strconv.Itoa(u.TimeMix), strconv.Itoa(u.Counter), strconv.Itoa(u.Salt), strconv.Itoa(u.Random))
+}
+
+// printer writes IDs in the selected output format. Warnings go to stderr,
+// or into the records in the json and ndjson formats: the json document's
+// top-level warnings array holds the command's own, and each record those
+// about its input. In ndjson, where there is no top level, every record
+// carries the command's warnings too.
+type printer struct {
+	format outputFormat
+	loc    *time.Location
+	warnOn bool
+	out    *bufio.Writer
+
+	warnings []string
+	records  []idRecord
+	header   bool
+}
+
+// newPrinter returns a printer for cmd's --output; format "" prints each ID
+// in the form the caller names.
+func newPrinter(cmd *cobra.Command) (*printer, error) {
+	format, err := selectedOutput(cmd)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := vizid.LoadLocation(viper.GetString("timezone"))
+	if err != nil {
+		return nil, err
+	}
+	return &printer{
+		format:   format,
+		loc:      loc,
+		warnOn:   viper.GetBool("warn"),
+		out:      bufio.NewWriter(os.Stdout),
+		warnings: []string{},
+	}, nil
+}
+
+// warn reports a warning about the whole command.
+func (p *printer) warn(msg string) {
+	if !p.warnOn {
+		return
+	}
+	if p.format.structured() {
+		p.warnings = append(p.warnings, msg)
+		return
+	}
+	p.out.Flush() // keep stdout and stderr in order on a terminal
+	fmt.Fprintln(os.Stderr, "WARN:", msg)
+}
+
+// notes reports notes about one input, prefixed with label if there is one.
+func (p *printer) notes(label string, notes []string) []string {
+	if !p.warnOn || len(notes) == 0 {
+		return nil
+	}
+	if p.format.structured() {
+		return notes
+	}
+	p.out.Flush()
+	for _, n := range notes {
+		if label != "" {
+			fmt.Fprintf(os.Stderr, "%s: note: %s\n", label, n)
+		} else {
+			fmt.Fprintln(os.Stderr, "note:", n)
+		}
+	}
+	return nil
+}
+
+// id prints id, in form natural when no format was selected. input, label
+// and notes describe what it was read from, for commands that read IDs.
+func (p *printer) id(id vizid.ID, natural outputFormat, input, label string, notes []string) error {
+	warnings := p.notes(label, notes)
+	format := p.format
+	if format == "" {
+		format = natural
+	}
+	switch format {
+	case outputVIZ:
+		_, err := fmt.Fprintln(p.out, id.VIZ())
+		return err
+	case outputASCII:
+		_, err := fmt.Fprintln(p.out, id.ASCII())
+		return err
+	case outputBoth:
+		_, err := fmt.Fprintf(p.out, "%s\t%s\n", id.VIZ(), id.ASCII())
+		return err
+	}
+	r := newIDRecord(id, p.loc)
+	r.Input, r.Warnings = input, warnings
+	return p.record(r)
+}
+
+// fail records an input that could not be read. The error itself is
+// reported by the caller; the structured formats also get a record, so
+// their output stays one record per input.
+func (p *printer) fail(input, label string, err error, notes []string) error {
+	warnings := p.notes(label, notes)
+	if !p.format.structured() {
+		return nil
+	}
+	return p.record(idRecord{Input: input, Warnings: warnings, Error: err.Error()})
+}
+
+func (p *printer) record(r idRecord) error {
+	switch p.format {
+	case outputJSON:
+		p.records = append(p.records, r)
+		return nil
+	case outputNDJSON:
+		if len(p.warnings) > 0 {
+			r.Warnings = append(p.warnings[:len(p.warnings):len(p.warnings)], r.Warnings...)
+		}
+		enc := json.NewEncoder(p.out)
+		enc.SetEscapeHTML(false) // '&' is a node prefix
+		return enc.Encode(r)
+	}
+	if !p.header {
+		p.header = true
+		fmt.Fprintln(p.out, strings.Join(tsvHeader, "\t"))
+	}
+	_, err := fmt.Fprintln(p.out, strings.Join(r.tsvRow(), "\t"))
+	return err
+}
+
+// close writes the json document, if that is the format, and flushes.
+func (p *printer) close() error {
+	if p.format == outputJSON {
+		if p.records == nil {
+			p.records = []idRecord{}
+		}
+		enc := json.NewEncoder(p.out)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(struct {
+			IDs      []idRecord `json:"ids"`
+			Warnings []string   `json:"warnings"`
+		}{p.records, p.warnings}); err != nil {
+			return err
+		}
+	}
+	return p.out.Flush()
+}
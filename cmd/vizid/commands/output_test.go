@@ -0,0 +1,157 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ryanl/vizid/pkg/vizid"
+)
+
+func TestOutputFormats(t *testing.T) {
+	const ascii = "20240229120000000-%GT007"
+	viz, err := vizid.Encode(ascii, vizid.DefaultAlphabet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(format string) string {
+		t.Helper()
+		stdout, _, err := execute(t, "", "convert", "-o", format, ascii, "bogus")
+		if err == nil {
+			t.Errorf("-o %s: want an error for the bad input", format)
+		}
+		return stdout
+	}
+
+	for format, want := range map[string]string{
+		"viz":   viz + "\n",
+		"ascii": ascii + "\n",
+		"both":  viz + "\t" + ascii + "\n",
+	} {
+		if got := run(format); got != want {
+			t.Errorf("-o %s: stdout = %q, want %q", format, got, want)
+		}
+	}
+
+	// tsv has a row per good input only, under the header
+	lines := strings.Split(strings.TrimSuffix(run("tsv"), "\n"), "\n")
+	if len(lines) != 2 || lines[0] != strings.Join(tsvHeader, "\t") {
+		t.Fatalf("-o tsv: got %q, want the header and one row", lines)
+	}
+	row := strings.Split(lines[1], "\t")
+	if len(row) != len(tsvHeader) || row[0] != viz || row[1] != ascii || row[5] != "%" || row[10] != "7" {
+		t.Errorf("-o tsv: row %q", row)
+	}
+
+	// json and ndjson have a record per input, the bad one carrying its error
+	check := func(format string, ids []idRecord) {
+		t.Helper()
+		if len(ids) != 2 {
+			t.Fatalf("-o %s: got %d records, want 2", format, len(ids))
+		}
+		good, bad := ids[0], ids[1]
+		if good.Input != ascii || good.VIZ != viz || good.ASCII != ascii || good.Error != "" ||
+			good.UUID == nil || good.UUID.Node != "%7" || good.Time != "2024-02-29T12:00:00.000Z" {
+			t.Errorf("-o %s: good record %+v", format, good)
+		}
+		if bad.Input != "bogus" || bad.Error == "" || bad.ASCII != "" {
+			t.Errorf("-o %s: bad record %+v", format, bad)
+		}
+	}
+	var doc struct {
+		IDs      []idRecord `json:"ids"`
+		Warnings []string   `json:"warnings"`
+	}
+	out := run("json")
+	if err := json.Unmarshal([]byte(out), &doc); err != nil {
+		t.Fatalf("-o json: %v in %q", err, out)
+	}
+	if doc.Warnings == nil || len(doc.Warnings) != 0 {
+		t.Errorf("-o json: warnings = %#v, want []", doc.Warnings)
+	}
+	check("json", doc.IDs)
+	check("ndjson", decodeNDJSON(t, run("ndjson")))
+
+	if _, _, err := execute(t, "", "convert", "-o", "yaml", ascii); err == nil || !strings.Contains(err.Error(), "unknown output format") {
+		t.Errorf("-o yaml: err = %v, want an unknown format error", err)
+	}
+}
+
+func TestOutputWarnings(t *testing.T) {
+	a, err := vizid.ParseASCII("20240229120000000-%GT007", vizid.DefaultAlphabet, vizid.LeapReject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := vizid.ParseASCII("20240229120000001-%GT007", vizid.DefaultAlphabet, vizid.LeapReject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	emit := func(format outputFormat) string {
+		t.Helper()
+		var buf bytes.Buffer
+		p := &printer{format: format, loc: time.UTC, warnOn: true, out: bufio.NewWriter(&buf), warnings: []string{}}
+		p.warn("command")
+		if err := p.id(a, outputVIZ, "a", "line 1", []string{"note a"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := p.id(b, outputVIZ, "b", "line 2", nil); err != nil {
+			t.Fatal(err)
+		}
+		if err := p.close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+
+	// json: the command's warnings at the top level, notes in their record
+	var doc struct {
+		IDs      []idRecord `json:"ids"`
+		Warnings []string   `json:"warnings"`
+	}
+	if err := json.Unmarshal([]byte(emit(outputJSON)), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if !equalStrings(doc.Warnings, []string{"command"}) || len(doc.IDs) != 2 ||
+		!equalStrings(doc.IDs[0].Warnings, []string{"note a"}) || len(doc.IDs[1].Warnings) != 0 {
+		t.Errorf("json: warnings %q, records %+v", doc.Warnings, doc.IDs)
+	}
+
+	// ndjson: every record repeats the command's warnings before its own
+	ids := decodeNDJSON(t, emit(outputNDJSON))
+	if len(ids) != 2 || !equalStrings(ids[0].Warnings, []string{"command", "note a"}) ||
+		!equalStrings(ids[1].Warnings, []string{"command"}) {
+		t.Errorf("ndjson: records %+v", ids)
+	}
+
+	// the line formats leave warnings to stderr
+	stdout, stderr, _ := execute(t, "2024\u200b0229120000000-%GT007\n", "convert", "--lenient", "-o", "ascii", "-")
+	if stdout != "20240229120000000-%GT007\n" || !strings.Contains(stderr, "line 1: ") || !strings.Contains(stderr, "note: removed 1 invisible") {
+		t.Errorf("-o ascii: stdout %q, stderr %q; want the note on stderr only", stdout, stderr)
+	}
+	stdout, stderr, _ = execute(t, "2024\u200b0229120000000-%GT007\n", "convert", "--lenient", "-o", "ndjson", "-")
+	if ids := decodeNDJSON(t, stdout); len(ids) != 1 || len(ids[0].Warnings) != 1 || stderr != "" {
+		t.Errorf("-o ndjson: stdout %q, stderr %q; want the note in the record only", stdout, stderr)
+	}
+	stdout, _, _ = execute(t, "2024\u200b0229120000000-%GT007\n", "convert", "--lenient", "--warn=false", "-o", "ndjson", "-")
+	if ids := decodeNDJSON(t, stdout); len(ids) != 1 || len(ids[0].Warnings) != 0 {
+		t.Errorf("--warn=false -o ndjson: stdout %q, want no warnings", stdout)
+	}
+}
+
+// decodeNDJSON decodes one idRecord per line of s.
+func decodeNDJSON(t *testing.T, s string) []idRecord {
+	t.Helper()
+	var ids []idRecord
+	for _, line := range strings.Split(strings.TrimSuffix(s, "\n"), "\n") {
+		var r idRecord
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("ndjson line %q: %v", line, err)
+		}
+		ids = append(ids, r)
+	}
+	return ids
+}
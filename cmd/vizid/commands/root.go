@@ -1,17 +1,21 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
 
+	"github.com/ryanl/vizid/pkg/vizid"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 var (
-	cfgFile  string
-	tzFlag   string
-	warnFlag bool
+	cfgFile      string
+	tzFlag       string
+	warnFlag     bool
+	alphabetFlag string
 )
 
 var rootCmd = &cobra.Command{
@@ -25,7 +29,10 @@ func SetVersion(v string) {
 }
 
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	// Interrupting a command that is waiting on the clock cancels the wait.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
@@ -37,9 +44,11 @@ func init() {
 	rootCmd.PersistentFlags().StringVarP(&cfgFile, "config", "c", "", "config file (default is ~/.config/vizid/config.yaml)")
 	rootCmd.PersistentFlags().StringVarP(&tzFlag, "timezone", "t", "UTC", "timezone (IANA name like America/Chicago, UTC offset like +02:00, or UTC)")
 	rootCmd.PersistentFlags().BoolVar(&warnFlag, "warn", true, "warn if sort order might be broken by custom component selection")
+	rootCmd.PersistentFlags().StringVarP(&alphabetFlag, "alphabet", "a", "geometric", "glyph alphabet, by name or name@version (see 'vizid alphabet list')")
 
 	_ = viper.BindPFlag("timezone", rootCmd.PersistentFlags().Lookup("timezone"))
 	_ = viper.BindPFlag("warn", rootCmd.PersistentFlags().Lookup("warn"))
+	_ = viper.BindPFlag("alphabet", rootCmd.PersistentFlags().Lookup("alphabet"))
 }
 
 func initConfig() {
@@ -56,6 +65,19 @@ func initConfig() {
 	viper.SetDefault("timezone", "UTC")
 	viper.SetDefault("warn", true)
 	viper.SetDefault("custom", false)
+	viper.SetDefault("alphabet", "geometric")
+	viper.SetDefault("clock_policy", "error")
+	viper.SetDefault("overflow", "wait")
+	viper.SetDefault("max_drift", "1s")
+	viper.SetDefault("node_id", "random")
+	viper.SetDefault("persist", false)
+	viper.SetDefault("state_file", "")
+	viper.SetDefault("mixer", "v1")
+	viper.SetDefault("mixer_secret", "")
+	viper.SetDefault("layout", "classic")
+	viper.SetDefault("leap_second", "reject")
+	viper.SetDefault("lenient", false)
+	viper.SetDefault("output", "")
 
 	// Components defaults
 	viper.SetDefault("components.year", true)
@@ -70,4 +92,40 @@ func initConfig() {
 	if err := viper.ReadInConfig(); err == nil {
 		// config loaded
 	}
+
+	configGlyphsErr = registerConfigGlyphs()
+}
+
+// configGlyphsErr holds the result of loading the `glyphs:` config section.
+// It is reported when an alphabet is selected rather than at startup, so
+// commands that do not touch glyphs still work with a broken section.
+var configGlyphsErr error
+
+// registerConfigGlyphs registers the user-defined alphabet from the
+// `glyphs:` config section, if there is one.
+func registerConfigGlyphs() error {
+	if !viper.IsSet("glyphs.core") && !viper.IsSet("glyphs.prefix") {
+		return nil
+	}
+	a, sorted, err := vizid.NewCustomAlphabet(vizid.GlyphSpec{
+		Name:   viper.GetString("glyphs.name"),
+		Core:   viper.GetStringSlice("glyphs.core"),
+		Prefix: viper.GetStringSlice("glyphs.prefix"),
+		Sort:   viper.GetBool("glyphs.sort"),
+	})
+	if err != nil {
+		return err
+	}
+	if sorted && viper.GetBool("warn") {
+		fmt.Fprintf(os.Stderr, "WARN: glyph set %q was re-sorted by code point; glyph values differ from the config order\n", a.Name)
+	}
+	return vizid.RegisterAlphabet(a)
+}
+
+// selectedAlphabet resolves the --alphabet flag / `alphabet:` config key.
+func selectedAlphabet() (*vizid.Alphabet, error) {
+	if configGlyphsErr != nil {
+		return nil, configGlyphsErr
+	}
+	return vizid.LookupAlphabet(viper.GetString("alphabet"))
 }
@@ -0,0 +1,270 @@
+package codec
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// PrefixSet is the ASCII UUID prefix set shared by every alphabet, in byte order.
+const PrefixSet = "!$%&*@^~"
+
+// DescriptorPrefix marks an extended UUID, ^DTTCC: the digit D after it
+// records how the rest was produced (see MixerID). Generators never use it
+// as a node prefix.
+const DescriptorPrefix = '^'
+
+// ContentPrefix marks a content-derived ID ~TTCCRC, whose TTCCR is a hash
+// of the content rather than time-mix, counter and salt, and whose last
+// digit is ContentMarker. Generators never use it as a node prefix.
+const ContentPrefix = '~'
+
+// ContentMarker is the digit closing a content-derived UUID. It makes the
+// UUID 7 wide, a shape no 1.x ID has, so a 1.x ID with node prefix
+// ContentPrefix is never taken for a content-derived one.
+const ContentMarker = 12 // 'C'
+
+// Alphabet is a named glyph set: a core-36 table for base-36 digits and a
+// prefix table for the UUID prefix. Alphabets are immutable once built.
+type Alphabet struct {
+	Name    string
+	Version int
+
+	core     []rune
+	prefix   map[byte]rune
+	coreVal  map[rune]int
+	prefixOf map[rune]byte
+	waivers  map[string]string // lint rule -> why its violations are accepted
+}
+
+// NewAlphabet builds an alphabet from a 36-glyph core table and a prefix table
+// keyed by the characters of PrefixSet. It checks structure only; use
+// VerifyOrdered to check that the tables preserve sort order.
+func NewAlphabet(name string, version int, core []rune, prefix map[byte]rune) (*Alphabet, error) {
+	if name == "" || strings.ContainsAny(name, "@ ") {
+		return nil, fmt.Errorf("invalid alphabet name: %q", name)
+	}
+	if version < 1 {
+		return nil, fmt.Errorf("alphabet %s: invalid version %d", name, version)
+	}
+	if len(core) != 36 {
+		return nil, fmt.Errorf("alphabet %s: core table has %d glyphs, want 36", name, len(core))
+	}
+	if len(prefix) != len(PrefixSet) {
+		return nil, fmt.Errorf("alphabet %s: prefix table has %d glyphs, want %d", name, len(prefix), len(PrefixSet))
+	}
+
+	a := &Alphabet{
+		Name:     name,
+		Version:  version,
+		core:     append([]rune(nil), core...),
+		prefix:   map[byte]rune{},
+		coreVal:  map[rune]int{},
+		prefixOf: map[rune]byte{},
+	}
+	for i, r := range core {
+		if _, dup := a.coreVal[r]; dup {
+			return nil, fmt.Errorf("alphabet %s: duplicate core glyph %q", name, string(r))
+		}
+		a.coreVal[r] = i
+	}
+	for i := 0; i < len(PrefixSet); i++ {
+		p := PrefixSet[i]
+		g, ok := prefix[p]
+		if !ok {
+			return nil, fmt.Errorf("alphabet %s: missing prefix glyph for %q", name, p)
+		}
+		if _, dup := a.prefixOf[g]; dup {
+			return nil, fmt.Errorf("alphabet %s: duplicate prefix glyph %q", name, string(g))
+		}
+		if _, clash := a.coreVal[g]; clash {
+			return nil, fmt.Errorf("alphabet %s: prefix glyph %q is also a core glyph", name, string(g))
+		}
+		a.prefix[p] = g
+		a.prefixOf[g] = p
+	}
+	return a, nil
+}
+
+func mustAlphabet(name string, version int, core []rune, prefix map[byte]rune) *Alphabet {
+	a, err := NewAlphabet(name, version, core, prefix)
+	if err != nil {
+		panic("codec: " + err.Error())
+	}
+	return a
+}
+
+// ID returns the registry key "name@version".
+func (a *Alphabet) ID() string {
+	return a.Name + "@" + strconv.Itoa(a.Version)
+}
+
+// Core returns a copy of the core-36 table.
+func (a *Alphabet) Core() []rune {
+	return append([]rune(nil), a.core...)
+}
+
+// Prefix returns a copy of the prefix table.
+func (a *Alphabet) Prefix() map[byte]rune {
+	m := make(map[byte]rune, len(a.prefix))
+	for k, v := range a.prefix {
+		m[k] = v
+	}
+	return m
+}
+
+func (a *Alphabet) CoreValToGlyph(val int) (rune, error) {
+	if val < 0 || val >= 36 {
+		return 0, fmt.Errorf("invalid base36 value: %d", val)
+	}
+	return a.core[val], nil
+}
+
+func (a *Alphabet) CoreGlyphToVal(g rune) (int, error) {
+	v, ok := a.coreVal[g]
+	if !ok {
+		return 0, fmt.Errorf("unknown glyph: %q", string(g))
+	}
+	return v, nil
+}
+
+// PrefixToGlyph maps a UUID prefix ASCII byte to its glyph.
+func (a *Alphabet) PrefixToGlyph(p byte) (rune, bool) {
+	g, ok := a.prefix[p]
+	return g, ok
+}
+
+// GlyphToPrefix maps a UUID prefix glyph to its ASCII byte.
+func (a *Alphabet) GlyphToPrefix(g rune) (byte, bool) {
+	p, ok := a.prefixOf[g]
+	return p, ok
+}
+
+// Default is the code-point-ordered geometric alphabet built from Core36Glyphs
+// and PrefixASCII.
+var Default = mustAlphabet("geometric", 2, Core36Glyphs, PrefixASCII)
+
+// Legacy is the original, unordered geometric alphabet. It is registered so old
+// IDs can still be decoded and migrated.
+var Legacy = mustAlphabet("geometric", 1, LegacyCore36Glyphs, LegacyPrefixASCII)
+
+// waive accepts a's violations of a lint rule for the reason why; see
+// LintAlphabet. It is for built-in alphabets, before they are registered.
+func (a *Alphabet) waive(rule, why string) *Alphabet {
+	if a.waivers == nil {
+		a.waivers = map[string]string{}
+	}
+	a.waivers[rule] = why
+	return a
+}
+
+// legacy reports whether a is a vizid 1.x alphabet, whose IDs all have a
+// classic PTTCCR UUID: 1.x used every prefix as a node prefix.
+func (a *Alphabet) legacy() bool { return a == Legacy }
+
+// registry holds the registered alphabets by ID. Register may run while
+// other goroutines look alphabets up, so every access holds registryMu.
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]*Alphabet{}
+)
+
+func init() {
+	if err := VerifyOrdered(Default.core, Default.prefix); err != nil {
+		panic("codec: default glyph table: " + err.Error())
+	}
+	for _, a := range []*Alphabet{Default, Legacy} {
+		if err := Register(a); err != nil {
+			panic("codec: " + err.Error())
+		}
+	}
+}
+
+// Register adds an alphabet to the registry. Registering the same name and
+// version twice is an error.
+func Register(a *Alphabet) error {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := registry[a.ID()]; ok {
+		return fmt.Errorf("alphabet %s already registered", a.ID())
+	}
+	registry[a.ID()] = a
+	return nil
+}
+
+// Lookup returns a registered alphabet. "name@version" selects an exact
+// version; a bare name selects the highest registered version.
+func Lookup(spec string) (*Alphabet, error) {
+	if spec == "" {
+		return Default, nil
+	}
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	if strings.Contains(spec, "@") {
+		if a, ok := registry[spec]; ok {
+			return a, nil
+		}
+		return nil, fmt.Errorf("unknown alphabet: %q", spec)
+	}
+	var best *Alphabet
+	for _, a := range registry {
+		if a.Name == spec && (best == nil || a.Version > best.Version) {
+			best = a
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("unknown alphabet: %q", spec)
+	}
+	return best, nil
+}
+
+// Alphabets returns a snapshot of the registered alphabets, sorted by name
+// then version.
+func Alphabets() []*Alphabet {
+	registryMu.RLock()
+	out := make([]*Alphabet, 0, len(registry))
+	for _, a := range registry {
+		out = append(out, a)
+	}
+	registryMu.RUnlock()
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Name != out[j].Name {
+			return out[i].Name < out[j].Name
+		}
+		return out[i].Version < out[j].Version
+	})
+	return out
+}
+
+// DecodeAnyVIZToASCII decodes viz with the first registered alphabet that
+// accepts it. prefer (if non-nil) is tried first, then Default, then the rest
+// in Alphabets() order. On failure the error from the first attempt is returned.
+func DecodeAnyVIZToASCII(viz string, prefer *Alphabet) (string, *Alphabet, error) {
+	var firstErr error
+	for _, a := range candidateAlphabets(prefer) {
+		id, err := parseVIZ(viz, a)
+		if err == nil {
+			return id.ASCII(), a, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return "", nil, firstErr
+}
+
+// candidateAlphabets is the detection order: prefer, Default, then the rest.
+func candidateAlphabets(prefer *Alphabet) []*Alphabet {
+	candidates := []*Alphabet{Default}
+	if prefer != nil && prefer != Default {
+		candidates = []*Alphabet{prefer, Default}
+	}
+	for _, a := range Alphabets() {
+		if a != prefer && a != Default {
+			candidates = append(candidates, a)
+		}
+	}
+	return candidates
+}
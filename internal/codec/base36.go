@@ -1,6 +1,9 @@
 package codec
 
-import "fmt"
+import (
+	"fmt"
+	"math"
+)
 
 const digits = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ"
 
@@ -24,13 +27,21 @@ func ToBase36(n int64, width int) (string, error) {
 	return string(buf), nil
 }
 
+// FromBase36 reads upper-case base-36 digits. It rejects an empty string
+// and values that overflow int64.
 func FromBase36(s string) (int64, error) {
+	if s == "" {
+		return 0, fmt.Errorf("empty base36 number")
+	}
 	var v int64
 	for _, r := range s {
-		idx := int64(indexOf(byte(r)))
-		if idx < 0 {
+		if r > 0x7f || indexOf(byte(r)) < 0 {
 			return 0, fmt.Errorf("invalid base36 digit: %q", r)
 		}
+		idx := int64(indexOf(byte(r)))
+		if v > (math.MaxInt64-idx)/36 {
+			return 0, fmt.Errorf("base36 number %q overflows int64", s)
+		}
 		v = v*36 + idx
 	}
 	return v, nil
@@ -0,0 +1,154 @@
+package codec
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// GlyphSpec describes a user-defined alphabet, usually read from the
+// `glyphs:` config section.
+type GlyphSpec struct {
+	Name   string
+	Core   []string // 36 single-glyph entries
+	Prefix []string // 8 single-glyph entries, assigned to PrefixSet in order
+	Sort   bool     // re-sort each table by code point instead of refusing it
+}
+
+// GlyphSetError lists every problem found in a GlyphSpec.
+type GlyphSetError struct {
+	Name     string
+	Problems []string
+}
+
+func (e *GlyphSetError) Error() string {
+	return fmt.Sprintf("glyph set %q rejected:\n  - %s", e.Name, strings.Join(e.Problems, "\n  - "))
+}
+
+// NewCustomAlphabet validates a GlyphSpec and builds an alphabet from it.
+//
+// Every glyph must be a single rune that is not whitespace, a control
+// character, a combining mark, '-', a path separator, or an ASCII letter or
+// digit, and must appear only once across both tables. If the tables are not
+// in code point order they are sorted when spec.Sort is set; otherwise the
+// spec is refused. The returned bool reports whether anything was re-sorted.
+func NewCustomAlphabet(spec GlyphSpec) (*Alphabet, bool, error) {
+	name := spec.Name
+	if name == "" {
+		name = "custom"
+	}
+	var problems []string
+	core := parseGlyphList("core", spec.Core, 36, &problems)
+	prefix := parseGlyphList("prefix", spec.Prefix, len(PrefixSet), &problems)
+
+	seen := map[rune]string{}
+	for _, t := range []struct {
+		table  string
+		glyphs []rune
+	}{{"core", core}, {"prefix", prefix}} {
+		for i, r := range t.glyphs {
+			where := fmt.Sprintf("%s[%d]", t.table, i)
+			if prev, dup := seen[r]; dup {
+				problems = append(problems, fmt.Sprintf("%s: %q (U+%04X) duplicates %s", where, string(r), r, prev))
+				continue
+			}
+			seen[r] = where
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, false, &GlyphSetError{Name: name, Problems: problems}
+	}
+
+	sorted := false
+	for _, t := range []struct {
+		table  string
+		glyphs []rune
+	}{{"core", core}, {"prefix", prefix}} {
+		out := unorderedPairs(t.glyphs)
+		if len(out) == 0 {
+			continue
+		}
+		if spec.Sort {
+			sort.Slice(t.glyphs, func(i, j int) bool { return t.glyphs[i] < t.glyphs[j] })
+			sorted = true
+			continue
+		}
+		for _, i := range out {
+			problems = append(problems, fmt.Sprintf("%s[%d]: %q (U+%04X) sorts before %s[%d] %q (U+%04X); reorder it or set glyphs.sort",
+				t.table, i, string(t.glyphs[i]), t.glyphs[i], t.table, i-1, string(t.glyphs[i-1]), t.glyphs[i-1]))
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, false, &GlyphSetError{Name: name, Problems: problems}
+	}
+
+	pm := make(map[byte]rune, len(PrefixSet))
+	for i := 0; i < len(PrefixSet); i++ {
+		pm[PrefixSet[i]] = prefix[i]
+	}
+	a, err := NewAlphabet(name, 1, core, pm)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := VerifyOrdered(a.core, a.prefix); err != nil {
+		return nil, false, &GlyphSetError{Name: name, Problems: []string{err.Error()}}
+	}
+	return a, sorted, nil
+}
+
+func parseGlyphList(table string, entries []string, want int, problems *[]string) []rune {
+	if len(entries) != want {
+		*problems = append(*problems, fmt.Sprintf("%s: got %d glyphs, want %d", table, len(entries), want))
+	}
+	out := make([]rune, 0, len(entries))
+	for i, s := range entries {
+		where := fmt.Sprintf("%s[%d]", table, i)
+		if utf8.RuneCountInString(s) != 1 {
+			*problems = append(*problems, fmt.Sprintf("%s: %q must be exactly one character", where, s))
+			continue
+		}
+		r, _ := utf8.DecodeRuneInString(s)
+		if reason := glyphRejection(r); reason != "" {
+			*problems = append(*problems, fmt.Sprintf("%s: %q (U+%04X) %s", where, s, r, reason))
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// glyphRejection returns why r can never be a VIZ glyph, or "" if it can.
+func glyphRejection(r rune) string {
+	switch {
+	case r == utf8.RuneError:
+		return "is not valid UTF-8"
+	case r == '-':
+		return "is the VIZID delimiter"
+	case r == '/' || r == '\\':
+		return "is a path separator"
+	case unicode.IsSpace(r):
+		return "is whitespace"
+	case unicode.IsControl(r):
+		return "is a control character"
+	case unicode.Is(unicode.M, r):
+		return "is a combining mark"
+	case r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+		return "is an ASCII letter or digit"
+	}
+	return ""
+}
+
+// unorderedPairs returns each index i whose glyph does not sort after glyph i-1.
+func unorderedPairs(glyphs []rune) []int {
+	var out []int
+	for i := 1; i < len(glyphs); i++ {
+		if glyphs[i] <= glyphs[i-1] {
+			out = append(out, i)
+		}
+	}
+	return out
+}
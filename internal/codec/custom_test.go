@@ -0,0 +1,84 @@
+package codec
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// glyphStrings returns each rune of rs as a one-glyph entry.
+func glyphStrings(rs []rune) []string {
+	out := make([]string, len(rs))
+	for i, r := range rs {
+		out[i] = string(r)
+	}
+	return out
+}
+
+func TestNewCustomAlphabet(t *testing.T) {
+	core := glyphStrings(RunesCore36Glyphs)
+	prefix := []string{"ᛮ", "ᛯ", "ᛰ", "ᛱ", "ᛲ", "ᛳ", "ᛴ", "ᛵ"}
+	with := func(entries []string, i int, s string) []string {
+		out := append([]string(nil), entries...)
+		out[i] = s
+		return out
+	}
+	reversed := make([]string, len(core))
+	for i, s := range core {
+		reversed[len(core)-1-i] = s
+	}
+
+	tests := []struct {
+		name       string
+		spec       GlyphSpec
+		wantErr    []string // substrings of the problems; nil for success
+		wantSorted bool
+	}{
+		{name: "ordered", spec: GlyphSpec{Core: core, Prefix: prefix}},
+		{name: "short core", spec: GlyphSpec{Core: core[:35], Prefix: prefix}, wantErr: []string{"core: got 35 glyphs, want 36"}},
+		{name: "two runes", spec: GlyphSpec{Core: with(core, 3, "ᚠ\uFE0E"), Prefix: prefix}, wantErr: []string{"core[3]", "exactly one character"}},
+		{name: "delimiter", spec: GlyphSpec{Core: core, Prefix: with(prefix, 0, "-")}, wantErr: []string{"prefix[0]", "delimiter"}},
+		{name: "path separator", spec: GlyphSpec{Core: with(core, 0, "/"), Prefix: prefix}, wantErr: []string{"path separator"}},
+		{name: "whitespace", spec: GlyphSpec{Core: with(core, 0, " "), Prefix: prefix}, wantErr: []string{"whitespace"}},
+		{name: "control", spec: GlyphSpec{Core: with(core, 0, "\x07"), Prefix: prefix}, wantErr: []string{"control character"}},
+		{name: "combining mark", spec: GlyphSpec{Core: with(core, 0, "\u0301"), Prefix: prefix}, wantErr: []string{"combining mark"}},
+		{name: "ASCII letter", spec: GlyphSpec{Core: with(core, 0, "A"), Prefix: prefix}, wantErr: []string{"ASCII letter or digit"}},
+		{name: "duplicate across tables", spec: GlyphSpec{Core: core, Prefix: with(prefix, 7, core[0])}, wantErr: []string{"prefix[7]", "duplicates core[0]"}},
+		{
+			name:    "every problem listed",
+			spec:    GlyphSpec{Core: with(with(core, 0, "A"), 1, "-"), Prefix: prefix[:7]},
+			wantErr: []string{"core[0]", "core[1]", "prefix: got 7 glyphs"},
+		},
+		{name: "unordered", spec: GlyphSpec{Core: reversed, Prefix: prefix}, wantErr: []string{"core[1]", "set glyphs.sort"}},
+		{name: "re-sorted", spec: GlyphSpec{Core: reversed, Prefix: prefix, Sort: true}, wantSorted: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, sorted, err := NewCustomAlphabet(tt.spec)
+			if tt.wantErr != nil {
+				var ge *GlyphSetError
+				if !errors.As(err, &ge) {
+					t.Fatalf("got %v, want a *GlyphSetError", err)
+				}
+				for _, want := range tt.wantErr {
+					if !strings.Contains(err.Error(), want) {
+						t.Errorf("%v: does not mention %q", err, want)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sorted != tt.wantSorted {
+				t.Errorf("sorted = %v, want %v", sorted, tt.wantSorted)
+			}
+			if a.ID() != "custom@1" || string(a.core) != string(RunesCore36Glyphs) {
+				t.Errorf("got %s with core %s, want custom@1 with the runes core", a.ID(), string(a.core))
+			}
+			if err := VerifyOrdered(a.core, a.prefix); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
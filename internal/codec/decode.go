@@ -0,0 +1,343 @@
+package codec
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ryanl/vizid/internal/timeutil"
+)
+
+// DecodeError reports why an ID was rejected and where. Index is the rune
+// offset of the offending glyph or character in the input, or of the end
+// of the input when something is missing; Glyph is the rune found there (0
+// when there is none). For a glyph the alphabet does not have, Suggest
+// lists the likeliest intended glyphs, closest first.
+type DecodeError struct {
+	Field   string // "year", "month", ..., "prefix", "descriptor", "counter", or "timestamp"/"uuid" for lengths
+	Index   int
+	Glyph   rune
+	Reason  string
+	Suggest []rune
+}
+
+func (e *DecodeError) Error() string {
+	msg := fmt.Sprintf("%s: %s (at %d)", e.Field, e.Reason, e.Index)
+	if e.Glyph != 0 {
+		msg = fmt.Sprintf("%s: %s (%q at %d)", e.Field, e.Reason, e.Glyph, e.Index)
+	}
+	if len(e.Suggest) > 0 {
+		msg += "; did you mean " + e.SuggestString() + "?"
+	}
+	return msg
+}
+
+// SuggestString renders Suggest as "◆, ◇ or ◈".
+func (e *DecodeError) SuggestString() string {
+	s := make([]string, len(e.Suggest))
+	for i, r := range e.Suggest {
+		s[i] = string(r)
+	}
+	if len(s) < 2 {
+		return strings.Join(s, "")
+	}
+	return strings.Join(s[:len(s)-1], ", ") + " or " + s[len(s)-1]
+}
+
+// tsField is one field of the 12 timestamp digits: its digit span, the
+// range of the stored value and the offset from stored to calendar value
+// (1 for the zero-based month and day).
+type tsField struct {
+	name       string
+	start, end int
+	max, base  int
+}
+
+var tsFields = [...]tsField{
+	{"year", 0, 3, 9999, 0},
+	{"month", 3, 4, 11, 1},
+	{"day", 4, 5, 30, 1},
+	{"hour", 5, 6, 23, 0},
+	{"minute", 6, 8, 59, 0},
+	{"second", 8, 10, 59, 0},
+	{"ms", 10, 12, 999, 0},
+}
+
+// uuidFieldAt names the field digit i (counting after the prefix) of a
+// classic UUID belongs to, for error reports; extended ones are "uuid".
+func uuidFieldAt(classic bool, i int) string {
+	if classic && i < 6 {
+		return [...]string{"time-mix", "time-mix", "counter", "counter", "salt", "mixer"}[i]
+	}
+	return "uuid"
+}
+
+// parseVIZ strictly reads <12 glyphs>[-<UUID glyphs>] in alphabet a: every
+// glyph must belong to a, every field must be in range and the date must
+// exist in the proleptic Gregorian calendar. Errors are *DecodeError.
+func parseVIZ(viz string, a *Alphabet) (ID, error) {
+	rs := []rune(viz)
+	dash := -1
+	for i, r := range rs {
+		if r != '-' {
+			continue
+		}
+		if dash >= 0 {
+			return ID{}, &DecodeError{Field: "delimiter", Index: i, Glyph: r, Reason: "more than one '-'"}
+		}
+		dash = i
+	}
+	tsGlyphs, uidGlyphs := rs, []rune(nil)
+	if dash >= 0 {
+		tsGlyphs, uidGlyphs = rs[:dash], rs[dash+1:]
+	}
+	if len(tsGlyphs) != 12 {
+		e := &DecodeError{Field: "timestamp", Index: len(tsGlyphs), Reason: fmt.Sprintf("got %d glyphs, want 12", len(tsGlyphs))}
+		if len(tsGlyphs) > 12 {
+			e.Index, e.Glyph = 12, tsGlyphs[12]
+		}
+		return ID{}, e
+	}
+
+	// Decode timestamp glyphs -> base36 values
+	var vals [12]int
+	for i, g := range tsGlyphs {
+		val, err := a.CoreGlyphToVal(g)
+		if err != nil {
+			return ID{}, &DecodeError{Field: fieldAt(i), Index: i, Glyph: g, Reason: fmt.Sprintf("not a glyph of alphabet %s", a.ID()), Suggest: suggest(g, a.core)}
+		}
+		vals[i] = val
+	}
+	var t [len(tsFields)]int
+	for k, f := range tsFields {
+		v := 0
+		for _, d := range vals[f.start:f.end] {
+			v = v*36 + d
+		}
+		if v > f.max {
+			i := overflowAt(vals[f.start:f.end], f.max) + f.start
+			return ID{}, &DecodeError{Field: f.name, Index: i, Glyph: tsGlyphs[i], Reason: fmt.Sprintf("value %d out of range %d..%d", v+f.base, f.base, f.max+f.base)}
+		}
+		t[k] = v + f.base
+	}
+	ts := Timestamp{Year: t[0], Month: t[1], Day: t[2], Hour: t[3], Minute: t[4], Second: t[5], Millis: t[6]}
+	if n := timeutil.DaysIn(ts.Year, ts.Month); ts.Day > n {
+		return ID{}, &DecodeError{Field: "day", Index: 4, Glyph: tsGlyphs[4], Reason: fmt.Sprintf("%04d-%02d has %d days, not %d", ts.Year, ts.Month, n, ts.Day)}
+	}
+	if uidGlyphs == nil {
+		return newID(ts, nil, a, nil), nil
+	}
+
+	// Decode UUID; at is the rune offset of its prefix glyph
+	at := dash + 1
+	if len(uidGlyphs) == 0 {
+		return ID{}, &DecodeError{Field: "prefix", Index: at, Reason: "missing after '-'"}
+	}
+	p, ok := a.GlyphToPrefix(uidGlyphs[0])
+	if !ok {
+		return ID{}, &DecodeError{Field: "prefix", Index: at, Glyph: uidGlyphs[0], Reason: fmt.Sprintf("not a prefix glyph of alphabet %s", a.ID()), Suggest: suggest(uidGlyphs[0], a.prefixGlyphs())}
+	}
+	legacy := a.legacy()
+	want, classic := uuidWidth(p, 0, len(uidGlyphs), legacy)
+	if p == DescriptorPrefix && !legacy {
+		if len(uidGlyphs) < 2 {
+			return ID{}, &DecodeError{Field: "descriptor", Index: at + 1, Reason: fmt.Sprintf("missing after %q", string(DescriptorPrefix))}
+		}
+		d, err := a.CoreGlyphToVal(uidGlyphs[1])
+		if err != nil {
+			return ID{}, &DecodeError{Field: "descriptor", Index: at + 1, Glyph: uidGlyphs[1], Reason: fmt.Sprintf("not a glyph of alphabet %s", a.ID()), Suggest: suggest(uidGlyphs[1], a.core)}
+		}
+		want, classic = uuidWidth(p, d, len(uidGlyphs), legacy)
+	}
+	if len(uidGlyphs) != want {
+		e := &DecodeError{Field: "uuid", Index: at + len(uidGlyphs), Reason: fmt.Sprintf("got %d glyphs, want %d", len(uidGlyphs), want)}
+		if len(uidGlyphs) > want {
+			e.Index, e.Glyph = at+want, uidGlyphs[want]
+		}
+		return ID{}, e
+	}
+	var buf [MaxUUIDWidth - 1]int
+	uv := buf[:len(uidGlyphs)-1]
+	for i := range uv {
+		g := uidGlyphs[i+1]
+		val, err := a.CoreGlyphToVal(g)
+		if err != nil {
+			return ID{}, &DecodeError{Field: uuidFieldAt(classic, i), Index: at + 1 + i, Glyph: g, Reason: fmt.Sprintf("not a glyph of alphabet %s", a.ID()), Suggest: suggest(g, a.core)}
+		}
+		uv[i] = val
+	}
+	u, err := uuidFromDigits(p, uv, classic)
+	if err != nil {
+		return ID{}, &DecodeError{Field: "uuid", Index: at, Glyph: uidGlyphs[0], Reason: err.Error()}
+	}
+	return newID(ts, &u, a, nil), nil
+}
+
+// fieldAt names the timestamp field digit i belongs to.
+func fieldAt(i int) string {
+	for _, f := range tsFields {
+		if i < f.end {
+			return f.name
+		}
+	}
+	return "timestamp"
+}
+
+// overflowAt returns the index of the digit at which ds, read as a base-36
+// number, first exceeds max written with as many digits: the most
+// significant digit greater than max's where all before it are equal.
+func overflowAt(ds []int, max int) int {
+	var md [MaxUUIDWidth]int
+	m := md[:len(ds)]
+	for i := len(m) - 1; i >= 0; i-- {
+		m[i] = max % 36
+		max /= 36
+	}
+	for i, d := range ds {
+		if d != m[i] {
+			return i
+		}
+	}
+	return len(ds) - 1
+}
+
+// LeapSecondPolicy says how ASCII input with second 60 (a leap second) is
+// read: the ID layout has no room for it.
+type LeapSecondPolicy string
+
+const (
+	LeapReject LeapSecondPolicy = "reject" // refuse it (the default)
+	LeapClamp  LeapSecondPolicy = "clamp"  // read it as hh:mm:59.999, the last instant before the next minute
+	LeapCarry  LeapSecondPolicy = "carry"  // carry it into the next minute, keeping the milliseconds
+)
+
+// ParseLeapSecondPolicy checks a policy name; empty means LeapReject.
+func ParseLeapSecondPolicy(s string) (LeapSecondPolicy, error) {
+	switch p := LeapSecondPolicy(s); p {
+	case "":
+		return LeapReject, nil
+	case LeapReject, LeapClamp, LeapCarry:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown leap second policy %q (want reject, clamp or carry)", s)
+}
+
+// asciiFields are the decimal timestamp fields of the wire form: their
+// byte span and calendar range. Day is checked against its month
+// separately.
+var asciiFields = [...]struct {
+	name       string
+	start, end int
+	min, max   int
+}{
+	{"year", 0, 4, 0, 9999},
+	{"month", 4, 6, 1, 12},
+	{"day", 6, 8, 1, 31},
+	{"hour", 8, 10, 0, 23},
+	{"minute", 10, 12, 0, 59},
+	{"second", 12, 14, 0, 60}, // 60 is left to the leap second policy
+	{"ms", 14, 17, 0, 999},
+}
+
+// parseASCII strictly reads YYYYMMDDhhmmssmmm[-UUID]: every field must be
+// in range and the date must exist in the proleptic Gregorian calendar;
+// second 60 is handled per leap. The UUID is read as a 1.x one when a is
+// a 1.x alphabet. Errors are *DecodeError.
+func parseASCII(ascii string, a *Alphabet, leap LeapSecondPolicy) (ID, error) {
+	dash := strings.IndexByte(ascii, '-')
+	ts, uuid := ascii, ""
+	if dash >= 0 {
+		ts, uuid = ascii[:dash], ascii[dash+1:]
+		if j := strings.IndexByte(uuid, '-'); j >= 0 {
+			return ID{}, &DecodeError{Field: "delimiter", Index: dash + 1 + j, Glyph: '-', Reason: "more than one '-'"}
+		}
+	}
+	if len(ts) != 17 {
+		e := &DecodeError{Field: "timestamp", Index: len(ts), Reason: fmt.Sprintf("got %d characters, want 17 (YYYYMMDDhhmmssmmm)", len(ts))}
+		if len(ts) > 17 {
+			e.Glyph = rune(ts[17])
+			e.Index = 17
+		}
+		return ID{}, e
+	}
+	var v [len(asciiFields)]int
+	for k, f := range asciiFields {
+		for i := f.start; i < f.end; i++ {
+			c := ts[i]
+			if c < '0' || c > '9' {
+				return ID{}, &DecodeError{Field: f.name, Index: i, Glyph: rune(c), Reason: "not a decimal digit"}
+			}
+			v[k] = v[k]*10 + int(c-'0')
+		}
+		if v[k] < f.min || v[k] > f.max {
+			return ID{}, &DecodeError{Field: f.name, Index: f.start, Glyph: rune(ts[f.start]), Reason: fmt.Sprintf("value %d out of range %d..%d", v[k], f.min, f.max)}
+		}
+	}
+	t := Timestamp{Year: v[0], Month: v[1], Day: v[2], Hour: v[3], Minute: v[4], Second: v[5], Millis: v[6]}
+	if n := timeutil.DaysIn(t.Year, t.Month); t.Day > n {
+		return ID{}, &DecodeError{Field: "day", Index: 6, Glyph: rune(ts[6]), Reason: fmt.Sprintf("%04d-%02d has %d days, not %d", t.Year, t.Month, n, t.Day)}
+	}
+	if t.Second == 60 {
+		switch leap {
+		case LeapClamp:
+			t.Second, t.Millis = 59, 999
+		case LeapCarry:
+			c := time.Date(t.Year, time.Month(t.Month), t.Day, t.Hour, t.Minute, 60, 0, time.UTC)
+			if c.Year() > 9999 {
+				return ID{}, &DecodeError{Field: "second", Index: 12, Glyph: '6', Reason: "leap second carries past year 9999"}
+			}
+			t.Year, t.Day, t.Hour, t.Minute, t.Second = c.Year(), c.Day(), c.Hour(), c.Minute(), c.Second()
+			t.Month = int(c.Month())
+		default:
+			return ID{}, &DecodeError{Field: "second", Index: 12, Glyph: '6', Reason: "leap second 60 is not allowed (policy reject; use clamp or carry)"}
+		}
+	}
+	if dash < 0 {
+		return newID(t, nil, a, nil), nil
+	}
+
+	// UUID; at is the offset of its prefix
+	at := dash + 1
+	if uuid == "" {
+		return ID{}, &DecodeError{Field: "prefix", Index: at, Reason: "missing after '-'"}
+	}
+	p := uuid[0]
+	if strings.IndexByte(PrefixSet, p) < 0 {
+		return ID{}, &DecodeError{Field: "prefix", Index: at, Glyph: rune(p), Reason: fmt.Sprintf("not one of %q", PrefixSet)}
+	}
+	legacy := a.legacy()
+	var buf [MaxUUIDWidth + 1]int
+	d := buf[:0]
+	for i := 1; i < len(uuid) && i <= MaxUUIDWidth; i++ {
+		x := indexOf(uuid[i])
+		if x < 0 {
+			field := "uuid"
+			if p == DescriptorPrefix && !legacy && i == 1 {
+				field = "descriptor"
+			} else if p != DescriptorPrefix || legacy {
+				field = uuidFieldAt(true, i-1)
+			}
+			return ID{}, &DecodeError{Field: field, Index: at + i, Glyph: rune(uuid[i]), Reason: "not a base-36 digit (0-9, A-Z)"}
+		}
+		d = append(d, x)
+	}
+	want, classic := uuidWidth(p, 0, len(uuid), legacy)
+	if p == DescriptorPrefix && !legacy {
+		if len(d) == 0 {
+			return ID{}, &DecodeError{Field: "descriptor", Index: at + 1, Reason: fmt.Sprintf("missing after %q", string(DescriptorPrefix))}
+		}
+		want, classic = uuidWidth(p, d[0], len(uuid), legacy)
+	}
+	if len(uuid) != want {
+		e := &DecodeError{Field: "uuid", Index: at + len(uuid), Reason: fmt.Sprintf("got %d characters, want %d", len(uuid), want)}
+		if len(uuid) > want {
+			e.Index, e.Glyph = at+want, rune(uuid[want])
+		}
+		return ID{}, e
+	}
+	u, err := uuidFromDigits(p, d, classic)
+	if err != nil {
+		return ID{}, &DecodeError{Field: "uuid", Index: at, Glyph: rune(p), Reason: err.Error()}
+	}
+	return newID(t, &u, a, nil), nil
+}
@@ -0,0 +1,193 @@
+package codec
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		in        string
+		wantField string // "" for no error
+		wantIndex int
+	}{
+		{in: "20240229120000000-%GT007"},
+		{in: "20240229120000000"},
+		{in: "20240229120000000-%GT0071"}, // keyed mixer
+		{in: "2024022912000000", wantField: "timestamp", wantIndex: 16},
+		{in: "20241329120000000-%GT007", wantField: "month", wantIndex: 4},
+		{in: "20250229120000000-%GT007", wantField: "day", wantIndex: 6},
+		{in: "20240229126000000-%GT007", wantField: "minute", wantIndex: 10},
+		{in: "20240229120000000-", wantField: "prefix", wantIndex: 18},
+		{in: "20240229120000000-#GT007", wantField: "prefix", wantIndex: 18},
+		{in: "20240229120000000-%GT00", wantField: "uuid", wantIndex: 23},
+		{in: "20240229120000000-%GT00a7", wantField: "salt", wantIndex: 23},
+		{in: "20240229120000000-%GT0070", wantField: "uuid", wantIndex: 18},  // v1 has no mixer digit
+		{in: "20240229120000000-~GT007C"},                                    // content-derived
+		{in: "20240229120000000-~GT007"},                                     // a 1.x node prefix
+		{in: "20240229120000000-~GT0071", wantField: "uuid", wantIndex: 18},  // content IDs end in C
+		{in: "20240229120000000-^ABCDE"},                                     // a 1.x node prefix
+		{in: "20240229120000000-^AGT0071", wantField: "uuid", wantIndex: 25}, // A is a 7-wide layout with no mixer digit
+		{in: "⊟◈▥⬥◒▷⊞⊞⊞⊞⊞⊞-✱◁◓⊞⊞▤", wantField: "month", wantIndex: 3},
+		{in: "⊟◈▥⊟◒▷⊞⊞⊞⊞⊞⊞-✱◁◓⊞⊞", wantField: "uuid", wantIndex: 18},
+		{in: "⊟◈▥⊟◒▷⊞⊞⊞⊞⊞⊞-✱◁◓⊞⊞▤-", wantField: "delimiter", wantIndex: 19},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			id, err := Parse(tt.in)
+			if tt.wantField == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := id.ASCII(); got != tt.in {
+					t.Errorf("round trip: got %s", got)
+				}
+				return
+			}
+			var de *DecodeError
+			if !errors.As(err, &de) {
+				t.Fatalf("got %v, want a *DecodeError", err)
+			}
+			if de.Field != tt.wantField || de.Index != tt.wantIndex {
+				t.Errorf("got %s at %d (%v), want %s at %d", de.Field, de.Index, err, tt.wantField, tt.wantIndex)
+			}
+		})
+	}
+}
+
+func TestParseLegacyDescriptorPrefix(t *testing.T) {
+	// 1 names a 4-wide layout, so the 6-wide UUID is a 1.x one
+	id, err := Parse("20240101120000000-^1A2B3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := id.UUID()
+	if id.IsExtended() || u.TimeMix != 1*36+10 || u.Counter != 2*36+11 || u.Salt != 3 {
+		t.Errorf("got %+v, extended %v; want the classic fields of 1A2B3", u, id.IsExtended())
+	}
+	back, err := Parse(id.VIZ())
+	if err != nil || back.ASCII() != id.ASCII() {
+		t.Errorf("VIZ round trip: got %s, %v", back.ASCII(), err)
+	}
+
+	// 3 names a 6-wide layout: only a 1.x alphabet reads it as 1.x
+	const ambiguous = "20240101120000000-^3ABCD"
+	if id, err := Parse(ambiguous); err != nil || !id.IsExtended() {
+		t.Errorf("Parse: got extended %v, %v; want an extended ID", id.IsExtended(), err)
+	}
+	id, err = ParseWith(ambiguous, Legacy)
+	if err != nil || id.IsExtended() {
+		t.Fatalf("ParseWith geometric@1: got extended %v, %v; want a classic ID", id.IsExtended(), err)
+	}
+	back, err = parseVIZ(id.VIZ(), Legacy)
+	if err != nil || back.ASCII() != ambiguous {
+		t.Errorf("geometric@1 VIZ round trip: got %s, %v", back.ASCII(), err)
+	}
+}
+
+func TestParseSuggestsGlyphs(t *testing.T) {
+	_, err := Parse("⊟◈▥⬥◒▷⊞⊞⊞⊞⊞⊞-✱◁◓⊞⊞▤")
+	var de *DecodeError
+	if !errors.As(err, &de) {
+		t.Fatalf("got %v, want a *DecodeError", err)
+	}
+	if len(de.Suggest) == 0 || de.Suggest[0] != '◆' {
+		t.Errorf("suggestions %q, want ◆ first", string(de.Suggest))
+	}
+}
+
+func TestParseASCIILeapSecond(t *testing.T) {
+	const in = "20161231235960500-%00000"
+	tests := []struct {
+		leap    LeapSecondPolicy
+		want    string
+		wantErr bool
+	}{
+		{leap: LeapReject, wantErr: true},
+		{leap: LeapClamp, want: "20161231235959999-%00000"},
+		{leap: LeapCarry, want: "20170101000000500-%00000"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.leap), func(t *testing.T) {
+			id, err := parseASCII(in, nil, tt.leap)
+			if tt.wantErr {
+				var de *DecodeError
+				if !errors.As(err, &de) || de.Field != "second" || de.Index != 12 {
+					t.Fatalf("got %v, want a second error at 12", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := id.ASCII(); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLenient(t *testing.T) {
+	tests := []struct {
+		name      string
+		in        string
+		leap      LeapSecondPolicy
+		want      string
+		wantNotes bool
+		wantField string
+	}{
+		{name: "clean", in: "20250102030405006-!ABCDE", want: "20250102030405006-!ABCDE"},
+		{name: "invisibles and lookalikes", in: "⊟◈▦⊞⊟⊡⊞⬛︎⊞◻⊞▣‐✦🔺△▷▶️▼", want: "20250102030405006-!ABCDE", wantNotes: true},
+		{name: "fullwidth digits", in: "２0250102030405006-!ABCDE", want: "20250102030405006-!ABCDE", wantNotes: true},
+		{name: "leap second rejected", in: "２0161231235960000-%00000", leap: LeapReject, wantNotes: true, wantField: "second"},
+		{name: "leap second clamped", in: "２0161231235960000-%00000", leap: LeapClamp, want: "20161231235959999-%00000", wantNotes: true},
+		{name: "leap second carried", in: "20161231235960000-%00000", leap: LeapCarry, want: "20170101000000000-%00000"},
+		{name: "unknown glyph", in: "⊟◈▦⊞⊟⊡⊞☃⊞□⊞▣-✦▲△▷►▼", wantField: "minute"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, notes, err := ParseLenient(tt.in, nil, tt.leap)
+			if (len(notes) > 0) != tt.wantNotes {
+				t.Errorf("notes %q, want some: %v", notes, tt.wantNotes)
+			}
+			if tt.wantField != "" {
+				var de *DecodeError
+				if !errors.As(err, &de) || de.Field != tt.wantField {
+					t.Fatalf("got %v, want a %s error", err, tt.wantField)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := id.ASCII(); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseContentMarker(t *testing.T) {
+	id, err := Parse("20240229120000000-~GT007C")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h, ok := id.ContentHash(); !ok || h != (16*36+29)*1296*36+7 {
+		t.Errorf("ContentHash() = %d, %v", h, ok)
+	}
+
+	// a 1.x ID with node prefix ~ is 6 wide and counter-based
+	id, err = Parse("20240229120000000-~GT007")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.IsContent() {
+		t.Error("a 6-wide ~ UUID is reported as content-derived")
+	}
+
+	// the 1.x alphabet has no content-derived IDs
+	content, _ := Parse("20240229120000000-~GT007C")
+	if _, err := parseVIZ(content.WithAlphabet(Legacy).VIZ(), Legacy); err == nil {
+		t.Error("geometric@1 decoded a 7-wide ~ UUID")
+	}
+}
@@ -1,25 +1,30 @@
 package codec
 
-import "fmt"
-
 // Core36Glyphs maps base-36 value -> rune glyph.
+//
+// Code points are strictly increasing with value, so sorting VIZ filenames
+// by code point (what `ls` does) matches chronological order.
 var Core36Glyphs = []rune{
-	'□','⊡','⊠','⊞','⊟','◫','◩','◪','■',
-	'◇','◈','◊','⟐','⟡','❖','⧫','◆','⬥',
-	'△','◬','◭','◮','⟁','▲','◢','◣','◤',
-	'○','◌','◍','◐','◑','◒','◓','◔','●',
+	'⊞', '⊟', '⊠', '⊡', '■', '□', '▣', '▤', '▥', '▦',
+	'▲', '△', '▷', '►', '▼', '▽', '◁', '◄',
+	'◆', '◇', '◈', '◊',
+	'○', '◍', '◎', '●', '◐', '◑', '◒', '◓', '◔', '◕', '◖', '◗', '◴', '◶',
 }
 
 // PrefixASCII maps UUID prefix ASCII -> glyph.
+//
+// Glyph code points increase with the ASCII byte order of the prefix, so
+// the VIZ and ASCII forms of an ID sort identically. ✳ and ✴ (U+2733,
+// U+2734) are skipped: they are emoji-capable.
 var PrefixASCII = map[byte]rune{
-	'~': '✦',
-	'!': '✧',
-	'@': '✱',
-	'$': '✲',
-	'%': '✳',
-	'^': '✴',
-	'&': '✵',
-	'*': '✶',
+	'!': '✦',
+	'$': '✧',
+	'%': '✱',
+	'&': '✲',
+	'*': '✵',
+	'@': '✶',
+	'^': '✷',
+	'~': '✸',
 }
 
 // PrefixGlyph maps UUID prefix glyph -> ASCII.
@@ -31,25 +36,33 @@ var PrefixGlyph = func() map[rune]byte {
 	return m
 }()
 
-var coreGlyphToVal = func() map[rune]int {
-	m := map[rune]int{}
-	for i, r := range Core36Glyphs {
-		m[r] = i
-	}
-	return m
-}()
+// LegacyCore36Glyphs is the original v1 core table. It is not in code point
+// order and is kept only so existing filenames can be migrated.
+var LegacyCore36Glyphs = []rune{
+	'□', '⊡', '⊠', '⊞', '⊟', '◫', '◩', '◪', '■',
+	'◇', '◈', '◊', '⟐', '⟡', '❖', '⧫', '◆', '⬥',
+	'△', '◬', '◭', '◮', '⟁', '▲', '◢', '◣', '◤',
+	'○', '◌', '◍', '◐', '◑', '◒', '◓', '◔', '●',
+}
 
+// LegacyPrefixASCII is the original v1 prefix table.
+var LegacyPrefixASCII = map[byte]rune{
+	'~': '✦',
+	'!': '✧',
+	'@': '✱',
+	'$': '✲',
+	'%': '✳',
+	'^': '✴',
+	'&': '✵',
+	'*': '✶',
+}
+
+// CoreValToGlyph maps a base-36 value to its glyph in the Default alphabet.
 func CoreValToGlyph(val int) (rune, error) {
-	if val < 0 || val >= 36 {
-		return 0, fmt.Errorf("invalid base36 value: %d", val)
-	}
-	return Core36Glyphs[val], nil
+	return Default.CoreValToGlyph(val)
 }
 
+// CoreGlyphToVal maps a Default alphabet glyph to its base-36 value.
 func CoreGlyphToVal(g rune) (int, error) {
-	v, ok := coreGlyphToVal[g]
-	if !ok {
-		return 0, fmt.Errorf("unknown glyph: %q", string(g))
-	}
-	return v, nil
+	return Default.CoreGlyphToVal(g)
 }
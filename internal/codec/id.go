@@ -0,0 +1,482 @@
+package codec
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ryanl/vizid/internal/timeutil"
+)
+
+// Timestamp holds the calendar fields of an ID. Month and Day are 1-based;
+// a zero Month or Day marks a component that was disabled at generation.
+type Timestamp struct {
+	Year, Month, Day             int
+	Hour, Minute, Second, Millis int
+}
+
+// UUID holds the fields of the PTTCCR[M] segment, or of an extended ^D...
+// segment, whose Layout says which fields are present. A 1.x UUID with node
+// prefix ^ is a classic one.
+type UUID struct {
+	Prefix  byte    // one of PrefixSet
+	Mixer   MixerID // how TT was produced; recorded by M or the descriptor
+	Layout  Layout  // extended UUIDs only; classic ones leave it zero
+	TimeMix int     // TT, 0..1295
+	Counter int     // CC, 0..1295 (0..36^Layout.Counter-1 when extended)
+	Salt    int     // R, 0..35; 0 for an extended UUID, which has no R
+	Random  int     // extended only: the random digits, 0..36^Layout.Random-1
+	Content bool    // a content-derived ~TTCCRC UUID, whose TTCCR is a hash
+}
+
+// MixerID identifies the function that produced TT. A classic UUID from
+// MixerV1 is PTTCCR; other mixers add a seventh digit M, their MixerID, so
+// the node prefix and salt stay. An extended ^D... UUID records the mixer
+// in its descriptor digit.
+type MixerID int
+
+const (
+	// MixerV1 is the fixed XOR/shift mix of the milliseconds within the
+	// minute.
+	MixerV1 MixerID = iota
+	// MixerKeyed is a keyed hash of the full timestamp and the node.
+	MixerKeyed
+)
+
+// String returns the mixer's name, as used by the `mixer` config key.
+func (m MixerID) String() string {
+	switch m {
+	case MixerV1:
+		return "v1"
+	case MixerKeyed:
+		return "keyed"
+	}
+	return fmt.Sprintf("mixer(%d)", int(m))
+}
+
+// ID is a parsed or generated VIZID. It is the single description of the
+// YYYYMMDDhhmmssmmm-PTTCCR layout: the ASCII and VIZ forms are both rendered
+// from its fields.
+type ID struct {
+	ts      Timestamp
+	uuid    UUID
+	hasUUID bool
+	alpha   *Alphabet
+	loc     *time.Location
+}
+
+// NewID builds an ID from its fields. uuid may be nil for a timestamp-only
+// ID. A nil alphabet means Default; a nil location means UTC.
+func NewID(ts Timestamp, uuid *UUID, a *Alphabet, loc *time.Location) (ID, error) {
+	type check struct {
+		name      string
+		v, lo, hi int
+	}
+	checks := [...]check{
+		{"year", ts.Year, 0, 9999},
+		{"month", ts.Month, 0, 12},
+		{"day", ts.Day, 0, 31},
+		{"hour", ts.Hour, 0, 23},
+		{"minute", ts.Minute, 0, 59},
+		{"second", ts.Second, 0, 59},
+		{"ms", ts.Millis, 0, 999},
+		{}, {}, {}, {}, // UUID fields
+	}
+	n := 7
+	if uuid != nil {
+		extended := uuid.Layout != (Layout{})
+		if strings.IndexByte(PrefixSet, uuid.Prefix) < 0 {
+			return ID{}, fmt.Errorf("unknown UUID prefix: %q", string(uuid.Prefix))
+		}
+		counters, randoms, mixes, salts := 36*36, 1, 36*36, 36
+		if extended {
+			if err := uuid.Layout.Validate(); err != nil {
+				return ID{}, err
+			}
+			counters, randoms, salts = uuid.Layout.CounterSpace(), uuid.Layout.RandomSpace(), 1
+			if !uuid.Layout.TimeMix {
+				mixes = 1
+			}
+		}
+		switch {
+		case uuid.Prefix == ContentPrefix && uuid.Mixer != MixerV1:
+			return ID{}, fmt.Errorf("mixer %s cannot be used with prefix %q", uuid.Mixer, string(ContentPrefix))
+		case uuid.Content && (uuid.Prefix != ContentPrefix || extended):
+			return ID{}, fmt.Errorf("content-derived UUIDs need prefix %q and the classic layout", string(ContentPrefix))
+		case extended && uuid.Prefix != DescriptorPrefix:
+			return ID{}, fmt.Errorf("layout %s needs prefix %q", uuid.Layout, string(DescriptorPrefix))
+		case !extended && uuid.Prefix == DescriptorPrefix && uuid.Mixer != MixerV1:
+			return ID{}, fmt.Errorf("mixer %s cannot be used with a 1.x UUID with prefix %q", uuid.Mixer, string(DescriptorPrefix))
+		case uuid.Mixer != MixerV1 && uuid.Mixer != MixerKeyed:
+			return ID{}, fmt.Errorf("unknown mixer %d", int(uuid.Mixer))
+		case extended && !uuid.Layout.TimeMix && uuid.Mixer != MixerV1:
+			return ID{}, fmt.Errorf("mixer %s needs a layout with time-mix", uuid.Mixer)
+		}
+		checks[7] = check{"time-mix", uuid.TimeMix, 0, mixes - 1}
+		checks[8] = check{"counter", uuid.Counter, 0, counters - 1}
+		checks[9] = check{"salt", uuid.Salt, 0, salts - 1}
+		checks[10] = check{"random", uuid.Random, 0, randoms - 1}
+		n = 11
+	}
+	for _, c := range checks[:n] {
+		if c.v < c.lo || c.v > c.hi {
+			return ID{}, fmt.Errorf("%s out of range: %d", c.name, c.v)
+		}
+	}
+	if ts.Month > 0 && ts.Day > timeutil.DaysIn(ts.Year, ts.Month) {
+		return ID{}, fmt.Errorf("day out of range: %04d-%02d has no day %d", ts.Year, ts.Month, ts.Day)
+	}
+	return newID(ts, uuid, a, loc), nil
+}
+
+func newID(ts Timestamp, uuid *UUID, a *Alphabet, loc *time.Location) ID {
+	if a == nil {
+		a = Default
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	id := ID{ts: ts, alpha: a, loc: loc}
+	if uuid != nil {
+		id.uuid = *uuid
+		id.hasUUID = true
+	}
+	return id
+}
+
+// Parse reads an ID in either form. ASCII input is recognised by being pure
+// ASCII; VIZ input is decoded with the first registered alphabet that fits
+// (see DecodeAnyVIZToASCII for the search order).
+func Parse(s string) (ID, error) {
+	return ParseWith(s, nil)
+}
+
+// ParseWith is Parse with a preferred alphabet for VIZ input. For ASCII
+// input the alphabet decides what VIZ() renders, and a 1.x alphabet reads
+// the UUID as a 1.x one.
+func ParseWith(s string, prefer *Alphabet) (ID, error) {
+	if isASCII(s) {
+		return parseASCII(s, prefer, LeapReject)
+	}
+	var firstErr error
+	for _, a := range candidateAlphabets(prefer) {
+		id, err := parseVIZ(s, a)
+		if err == nil {
+			return id, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return ID{}, firstErr
+}
+
+func firstByte(s string) byte {
+	if s == "" {
+		return 0
+	}
+	return s[0]
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// Timestamp returns the calendar fields.
+func (id ID) Timestamp() Timestamp { return id.ts }
+
+// Time returns the instant the timestamp names, reading its wall clock in the
+// ID's location (UTC for parsed IDs). A zeroed month or day reads as 1.
+func (id ID) Time() time.Time {
+	return id.TimeIn(id.loc)
+}
+
+// TimeIn reads the timestamp's wall clock in loc.
+func (id ID) TimeIn(loc *time.Location) time.Time {
+	month, day := id.ts.Month, id.ts.Day
+	if month == 0 {
+		month = 1
+	}
+	if day == 0 {
+		day = 1
+	}
+	return time.Date(id.ts.Year, time.Month(month), day,
+		id.ts.Hour, id.ts.Minute, id.ts.Second, id.ts.Millis*int(time.Millisecond), loc)
+}
+
+// HasUUID reports whether the ID has a PTTCCR segment.
+func (id ID) HasUUID() bool { return id.hasUUID }
+
+// UUID returns the PTTCCR fields; the zero UUID if HasUUID is false.
+func (id ID) UUID() UUID { return id.uuid }
+
+// Prefix returns the UUID prefix P as its ASCII byte.
+func (id ID) Prefix() byte { return id.uuid.Prefix }
+
+// TimeMix returns TT, the opaque time-derived value.
+func (id ID) TimeMix() int { return id.uuid.TimeMix }
+
+// Counter returns CC, the monotonic counter within the millisecond.
+func (id ID) Counter() int { return id.uuid.Counter }
+
+// Salt returns R, the salt digit; 0 for an extended UUID.
+func (id ID) Salt() int { return id.uuid.Salt }
+
+// Random returns the random digits of an extended UUID as one value.
+func (id ID) Random() int { return id.uuid.Random }
+
+// Layout returns the UUID layout: the extended UUID's, or ClassicLayout
+// for a classic PTTCCR one.
+func (id ID) Layout() Layout {
+	if id.IsExtended() {
+		return id.uuid.Layout
+	}
+	return ClassicLayout
+}
+
+// Mixer returns the function that produced TT.
+func (id ID) Mixer() MixerID { return id.uuid.Mixer }
+
+// IsExtended reports whether the UUID is an extended ^D... one; a 1.x
+// UUID with node prefix ^ is not.
+func (id ID) IsExtended() bool { return id.hasUUID && id.uuid.Layout != (Layout{}) }
+
+// IsContent reports whether the ID is content-derived (~TTCCRC): its TTCCR
+// is a content hash, and TimeMix, Counter and Salt are slices of that hash
+// rather than generator state. A 1.x ID with node prefix ContentPrefix is
+// 6 wide and not content-derived.
+func (id ID) IsContent() bool { return id.hasUUID && id.uuid.Content }
+
+// ContentHash returns the 5-digit base-36 hash (0..36^5-1) of a
+// content-derived ID; ok is false for other IDs.
+func (id ID) ContentHash() (hash int, ok bool) {
+	if !id.IsContent() {
+		return 0, false
+	}
+	return (id.uuid.TimeMix*1296+id.uuid.Counter)*36 + id.uuid.Salt, true
+}
+
+// Alphabet returns the alphabet VIZ() renders with.
+func (id ID) Alphabet() *Alphabet { return id.alpha }
+
+// WithAlphabet returns a copy of id that renders with a (nil means Default).
+func (id ID) WithAlphabet(a *Alphabet) ID {
+	if a == nil {
+		a = Default
+	}
+	id.alpha = a
+	return id
+}
+
+// ASCII renders the wire form YYYYMMDDhhmmssmmm[-PTTCCR].
+func (id ID) ASCII() string {
+	return string(id.AppendASCII(make([]byte, 0, 17+1+MaxUUIDWidth)))
+}
+
+// AppendASCII appends the wire form to dst. It does not allocate when dst
+// has room, so hot loops can reuse one buffer.
+func (id ID) AppendASCII(dst []byte) []byte {
+	t := id.ts
+	dst = appendDec(dst, t.Year, 4)
+	dst = appendDec(dst, t.Month, 2)
+	dst = appendDec(dst, t.Day, 2)
+	dst = appendDec(dst, t.Hour, 2)
+	dst = appendDec(dst, t.Minute, 2)
+	dst = appendDec(dst, t.Second, 2)
+	dst = appendDec(dst, t.Millis, 3)
+	if !id.hasUUID {
+		return dst
+	}
+	var ud [MaxUUIDWidth - 1]byte
+	n := id.uuidDigits(&ud)
+	dst = append(dst, '-', id.uuid.Prefix)
+	return append(dst, ud[:n]...)
+}
+
+// VIZ renders the glyph form with the ID's alphabet.
+func (id ID) VIZ() string {
+	return string(id.AppendVIZ(make([]byte, 0, (12+MaxUUIDWidth)*3+1)))
+}
+
+// AppendVIZ appends the glyph form, UTF-8 encoded, to dst. Like AppendASCII
+// it does not allocate when dst has room.
+func (id ID) AppendVIZ(dst []byte) []byte {
+	var ts [12]byte
+	id.timestampDigits(&ts)
+	for _, d := range ts {
+		dst = utf8.AppendRune(dst, id.alpha.core[indexOf(d)])
+	}
+	if !id.hasUUID {
+		return dst
+	}
+	pg, _ := id.alpha.PrefixToGlyph(id.uuid.Prefix)
+	dst = append(dst, '-')
+	dst = utf8.AppendRune(dst, pg)
+	var ud [MaxUUIDWidth - 1]byte
+	n := id.uuidDigits(&ud)
+	for _, d := range ud[:n] {
+		dst = utf8.AppendRune(dst, id.alpha.core[indexOf(d)])
+	}
+	return dst
+}
+
+func (id ID) String() string { return id.VIZ() }
+
+// timestampDigits writes the 12 base-36 digits of the timestamp.
+// Month and day are stored zero-based; a zeroed component stores 0.
+func (id ID) timestampDigits(buf *[12]byte) {
+	t := id.ts
+	month0, day0 := t.Month-1, t.Day-1
+	if month0 < 0 {
+		month0 = 0
+	}
+	if day0 < 0 {
+		day0 = 0
+	}
+	putB36(buf[0:3], t.Year)
+	putB36(buf[3:4], month0)
+	putB36(buf[4:5], day0)
+	putB36(buf[5:6], t.Hour)
+	putB36(buf[6:8], t.Minute)
+	putB36(buf[8:10], t.Second)
+	putB36(buf[10:12], t.Millis)
+}
+
+// Widths of the UUID segment in glyphs, prefix included: classic PTTCCR
+// is 6, or 7 with a mixer digit; extended ones run from ^DC to ^DTTCCCRRR.
+const (
+	MinUUIDWidth = 3
+	MaxUUIDWidth = 2 + 2 + MaxCounterDigits + MaxRandomDigits
+)
+
+// uuidDigits writes the base-36 digits after the prefix: TTCCR and the
+// mixer digit unless it is MixerV1, TTCCR and ContentMarker for a
+// content-derived UUID, or D and the layout's fields for an extended UUID.
+// It returns the digit count.
+func (id ID) uuidDigits(buf *[MaxUUIDWidth - 1]byte) int {
+	u := id.uuid
+	if !id.IsExtended() {
+		putB36(buf[0:2], u.TimeMix)
+		putB36(buf[2:4], u.Counter)
+		putB36(buf[4:5], u.Salt)
+		switch {
+		case u.Content:
+			putB36(buf[5:6], ContentMarker)
+		case u.Mixer == MixerV1:
+			return 5
+		default:
+			putB36(buf[5:6], int(u.Mixer))
+		}
+		return 6
+	}
+	l := u.Layout
+	putB36(buf[0:1], descriptor(l, u.Mixer))
+	n := 1
+	if l.TimeMix {
+		putB36(buf[n:n+2], u.TimeMix)
+		n += 2
+	}
+	putB36(buf[n:n+l.Counter], u.Counter)
+	n += l.Counter
+	putB36(buf[n:n+l.Random], u.Random)
+	return n + l.Random
+}
+
+// uuidWidth returns the UUID glyph width announced by a prefix and, for
+// DescriptorPrefix, the descriptor digit that follows it, and whether the
+// UUID is a classic PTTCCR[M] one. n is the width found: a classic UUID
+// may be 6 or 7 wide, the latter with a mixer digit or, after
+// ContentPrefix, ContentMarker. legacy is set for input in a 1.x alphabet,
+// where every UUID is a 6-wide PTTCCR.
+//
+// vizid 1.x also used DescriptorPrefix as a node prefix, so a 6-wide UUID
+// whose descriptor announces another width is such a classic one.
+func uuidWidth(p byte, d, n int, legacy bool) (width int, classic bool) {
+	switch {
+	case legacy:
+		return 6, true
+	case p != DescriptorPrefix:
+		if n == 7 {
+			return 7, true
+		}
+		return 6, true
+	}
+	l, _, _ := fromDescriptor(d) // every digit names a layout
+	if w := l.Width(); w != 6 && n == 6 {
+		return 6, true
+	}
+	return l.Width(), false
+}
+
+// uuidFromDigits builds the UUID for prefix p and the digit values after
+// it; d has the width uuidWidth announced, and classic is its verdict.
+func uuidFromDigits(p byte, d []int, classic bool) (UUID, error) {
+	num := func(ds []int) int {
+		v := 0
+		for _, x := range ds {
+			v = v*36 + x
+		}
+		return v
+	}
+	if classic {
+		u := UUID{Prefix: p, TimeMix: num(d[0:2]), Counter: num(d[2:4]), Salt: d[4]}
+		switch {
+		case len(d) == 6 && p == ContentPrefix:
+			if d[5] != ContentMarker {
+				return UUID{}, fmt.Errorf("content-derived UUID ends in %s, want %s", string(digits[d[5]]), string(digits[ContentMarker]))
+			}
+			u.Content = true
+		case len(d) == 6:
+			u.Mixer = MixerID(d[5])
+			if u.Mixer == MixerV1 || u.Mixer > MixerKeyed {
+				return UUID{}, fmt.Errorf("unknown mixer digit %s", string(digits[d[5]]))
+			}
+		}
+		return u, nil
+	}
+	l, m, err := fromDescriptor(d[0])
+	if err != nil {
+		return UUID{}, err
+	}
+	u := UUID{Prefix: p, Mixer: m, Layout: l}
+	d = d[1:]
+	if l.TimeMix {
+		u.TimeMix, d = num(d[:2]), d[2:]
+	}
+	u.Counter, u.Random = num(d[:l.Counter]), num(d[l.Counter:])
+	return u, nil
+}
+
+// putB36 fills dst with v in exactly len(dst) base-36 digits; callers keep
+// v in range (an out-of-range value renders as '?').
+func putB36(dst []byte, v int) {
+	ok := v >= 0
+	for i := len(dst) - 1; i >= 0 && ok; i-- {
+		dst[i] = digits[v%36]
+		v /= 36
+	}
+	if !ok || v != 0 {
+		for i := range dst {
+			dst[i] = '?'
+		}
+	}
+}
+
+// appendDec appends v as exactly width zero-padded decimal digits.
+func appendDec(dst []byte, v, width int) []byte {
+	n := len(dst)
+	for i := 0; i < width; i++ {
+		dst = append(dst, '0')
+	}
+	for i := len(dst) - 1; i >= n; i-- {
+		dst[i] = byte('0' + v%10)
+		v /= 10
+	}
+	return dst
+}
@@ -0,0 +1,126 @@
+package codec
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Layout is the shape of an extended UUID after ^D: an optional 2-digit
+// time-mix, Counter counter digits and Random random digits. The descriptor
+// digit D records the layout and the mixer, so a decoder needs nothing else
+// to split the digits.
+type Layout struct {
+	TimeMix bool // TT present
+	Counter int  // counter digits, 1..MaxCounterDigits
+	Random  int  // random digits, 0..MaxRandomDigits
+}
+
+// Layout limits; together with the three time-mix modes (none, v1, keyed)
+// they fill the 36 values of the descriptor digit exactly.
+const (
+	MaxCounterDigits = 3
+	MaxRandomDigits  = 3
+)
+
+// ClassicLayout is the layout of the classic PTTCCR UUID body: time-mix and
+// a 2-digit counter. In the classic form R is the node's salt, not a random
+// digit.
+var ClassicLayout = Layout{TimeMix: true, Counter: 2}
+
+// Validate checks the digit counts.
+func (l Layout) Validate() error {
+	if l.Counter < 1 || l.Counter > MaxCounterDigits {
+		return fmt.Errorf("counter digits out of range 1..%d: %d", MaxCounterDigits, l.Counter)
+	}
+	if l.Random < 0 || l.Random > MaxRandomDigits {
+		return fmt.Errorf("random digits out of range 0..%d: %d", MaxRandomDigits, l.Random)
+	}
+	return nil
+}
+
+// Width is the glyph width of an extended UUID with this layout, from ^
+// to the last random digit.
+func (l Layout) Width() int {
+	w := 2 + l.Counter + l.Random
+	if l.TimeMix {
+		w += 2
+	}
+	return w
+}
+
+// CounterSpace is the number of counter values per millisecond, 36^Counter.
+func (l Layout) CounterSpace() int { return pow36(l.Counter) }
+
+// RandomSpace is the number of random values, 36^Random.
+func (l Layout) RandomSpace() int { return pow36(l.Random) }
+
+// String writes the layout as ParseLayout reads it, e.g. "tt+c2+r1".
+func (l Layout) String() string {
+	s := "c" + strconv.Itoa(l.Counter) + "+r" + strconv.Itoa(l.Random)
+	if l.TimeMix {
+		s = "tt+" + s
+	}
+	return s
+}
+
+// ParseLayout reads a layout written as "+"-separated parts: "tt" for
+// time-mix, "cN" for N counter digits and "rN" for N random digits, e.g.
+// "tt+c2+r1" or "c3". The counter defaults to 2 digits, random to 0 digits.
+// "classic" is ClassicLayout.
+func ParseLayout(s string) (Layout, error) {
+	if s == "classic" {
+		return ClassicLayout, nil
+	}
+	l := Layout{Counter: 2}
+	for _, part := range strings.Split(s, "+") {
+		switch {
+		case part == "tt":
+			l.TimeMix = true
+		case len(part) == 2 && (part[0] == 'c' || part[0] == 'r') && part[1] >= '0' && part[1] <= '9':
+			if part[0] == 'c' {
+				l.Counter = int(part[1] - '0')
+			} else {
+				l.Random = int(part[1] - '0')
+			}
+		default:
+			return Layout{}, fmt.Errorf("invalid UUID layout %q: unknown part %q (want tt, cN or rN)", s, part)
+		}
+	}
+	if err := l.Validate(); err != nil {
+		return Layout{}, fmt.Errorf("invalid UUID layout %q: %w", s, err)
+	}
+	return l, nil
+}
+
+// descriptor returns the digit D for a layout and mixer:
+// mode*12 + (Counter-1)*4 + Random, where mode is 0 without time-mix, 1
+// for the v1 mixer and 2 for the keyed mixer.
+func descriptor(l Layout, m MixerID) int {
+	mode := 0
+	if l.TimeMix {
+		mode = 1 + int(m)
+	}
+	return mode*12 + (l.Counter-1)*4 + l.Random
+}
+
+// fromDescriptor inverts descriptor.
+func fromDescriptor(d int) (Layout, MixerID, error) {
+	if d < 0 || d > 35 {
+		return Layout{}, 0, fmt.Errorf("unknown UUID descriptor %d", d)
+	}
+	l := Layout{TimeMix: d >= 12, Counter: d%12/4 + 1, Random: d % 4}
+	m := MixerV1
+	if d >= 24 {
+		m = MixerKeyed
+	}
+	return l, m, nil
+}
+
+func pow36(n int) int {
+	v := 1
+	for i := 0; i < n; i++ {
+		v *= 36
+	}
+	return v
+}
@@ -0,0 +1,64 @@
+package codec
+
+import "testing"
+
+func TestDescriptorRoundTrip(t *testing.T) {
+	for d := 0; d < 36; d++ {
+		l, m, err := fromDescriptor(d)
+		if err != nil {
+			t.Fatalf("%d: %v", d, err)
+		}
+		if err := l.Validate(); err != nil {
+			t.Errorf("%d: %s: %v", d, l, err)
+		}
+		if got := descriptor(l, m); got != d {
+			t.Errorf("descriptor(%s, %s) = %d, want %d", l, m, got, d)
+		}
+		back, err := ParseLayout(l.String())
+		if err != nil || back != l {
+			t.Errorf("ParseLayout(%q) = %v, %v", l.String(), back, err)
+		}
+	}
+	for _, d := range []int{-1, 36} {
+		if _, _, err := fromDescriptor(d); err == nil {
+			t.Errorf("fromDescriptor(%d): want an error", d)
+		}
+	}
+}
+
+func TestParseLayout(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Layout
+		wantErr bool
+	}{
+		{in: "classic", want: ClassicLayout},
+		{in: "tt+c2+r1", want: Layout{TimeMix: true, Counter: 2, Random: 1}},
+		{in: "c3", want: Layout{Counter: 3}},
+		{in: "r3+tt", want: Layout{TimeMix: true, Counter: 2, Random: 3}},
+		{in: "tt", want: Layout{TimeMix: true, Counter: 2}},
+		{in: "c0", wantErr: true},
+		{in: "c4", wantErr: true},
+		{in: "r4", wantErr: true},
+		{in: "c12", wantErr: true},
+		{in: "tt+x1", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseLayout(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %s, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package codec
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// confusables maps lookalikes that chat apps, PDFs and emoji fonts put in
+// place of alphabet glyphs to the canonical glyph. A mapping only applies to
+// runes the alphabet being decoded does not have, so a lookalike that is
+// itself a glyph (◇ and ◊ in geometric@2, say) keeps its own value.
+var confusables = map[rune]rune{
+	// squares
+	'⬛': '■', '◼': '■', '◾': '■', '▪': '■',
+	'⬜': '□', '◻': '□', '◽': '□', '▫': '□', '☐': '□',
+	// triangles
+	'▴': '▲', '⏶': '▲', '🔺': '▲',
+	'▵': '△',
+	'▶': '►', '▸': '►', '⏵': '►',
+	'▹': '▷', '▻': '▷',
+	'▾': '▼', '⏷': '▼', '🔻': '▼',
+	'▿': '▽',
+	'◀': '◄', '◂': '◄', '⏴': '◄',
+	'◃': '◁', '◅': '◁',
+	// diamonds
+	'⬥': '◆', '⧫': '◆', '♦': '◆', '🔶': '◆', '🔷': '◆', '🔸': '◆', '🔹': '◆',
+	'⬦': '◇', '♢': '◇', '⋄': '◇',
+	'⟐': '◈',
+	'⬨': '◊',
+	// circles
+	'◯': '○', '⚪': '○', '⭘': '○',
+	'⚫': '●', '⬤': '●', '⏺': '●',
+	'◉': '◎', '⊙': '◎',
+	// the delimiter
+	'‐': '-', '‑': '-', '‒': '-', '–': '-', '—': '-', '−': '-', '﹣': '-', '－': '-',
+}
+
+// shapeFamilies group glyphs and their lookalikes by visual family, for
+// "did you mean" suggestions.
+var shapeFamilies = [...]struct{ name, glyphs string }{
+	{"square", "⊞⊟⊠⊡■□▢▣▤▥▦▧▨▩▪▫▬▭▮▯◘◙◚◛◧◨◩◪◫◰◱◲◳◻◼◽◾⬛⬜☐☑☒⧆⧇⧈"},
+	{"triangle", "▲△▴▵▶▷▸▹►▻▼▽▾▿◀◁◂◃◄◅◢◣◤◥◬◭◮◸◹◺◿⟁⊿⏴⏵⏶⏷🔺🔻"},
+	{"diamond", "◆◇◈◊⬥⬦⬧⬨♦♢⋄⟐⟡❖⧫🔶🔷🔸🔹"},
+	{"circle", "○◌◍◎●◐◑◒◓◔◕◖◗◴◵◶◷◉◯⚪⚫⬤⊙⊚⊛⭘⏺"},
+	{"star", "✦✧✱✲✳✴✵✶✷✸✹✺✻✼✽✾✿❀❁❂❃❄❅❆❇❈❉❊❋★☆⭐*"},
+}
+
+// shapeFamily returns the visual family of r, or "" if it has none.
+func shapeFamily(r rune) string {
+	for _, f := range shapeFamilies {
+		if strings.ContainsRune(f.glyphs, r) {
+			return f.name
+		}
+	}
+	return ""
+}
+
+// maxSuggestions caps the "did you mean" list.
+const maxSuggestions = 3
+
+// suggest ranks candidates as replacements for the unknown rune r: the
+// glyph r is a known confusable of first, then the rest of its visual
+// family by code point distance, which keeps shape variants of one Unicode
+// block together. Without a family, the nearest code points are offered.
+func suggest(r rune, candidates []rune) []rune {
+	target, confusable := confusables[r]
+	fam := shapeFamily(r)
+	if fam == "" && confusable {
+		fam = shapeFamily(target)
+	}
+	rank := func(c rune) (int, rune) {
+		d := c - r
+		if d < 0 {
+			d = -d
+		}
+		switch {
+		case confusable && c == target:
+			return 0, d
+		case fam != "" && shapeFamily(c) == fam:
+			return 1, d
+		}
+		return 2, d
+	}
+	ranked := append([]rune(nil), candidates...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		ci, di := rank(ranked[i])
+		cj, dj := rank(ranked[j])
+		if ci != cj {
+			return ci < cj
+		}
+		return di < dj
+	})
+	// keep to the related glyphs when there are any
+	n := min(len(ranked), maxSuggestions)
+	if n > 0 {
+		if c, _ := rank(ranked[0]); c < 2 {
+			for i := 1; i < n; i++ {
+				if c, _ := rank(ranked[i]); c == 2 {
+					n = i
+					break
+				}
+			}
+		}
+	}
+	return ranked[:n]
+}
+
+// prefixGlyphs returns a's prefix glyphs in PrefixSet order.
+func (a *Alphabet) prefixGlyphs() []rune {
+	gs := make([]rune, 0, len(PrefixSet))
+	for i := 0; i < len(PrefixSet); i++ {
+		gs = append(gs, a.prefix[PrefixSet[i]])
+	}
+	return gs
+}
+
+// invisible reports whether r is dropped by lenient decoding: format
+// characters (zero-width spaces and joiners, bidi marks, BOM, soft hyphen)
+// and variation selectors such as U+FE0F.
+func invisible(r rune) bool {
+	return unicode.Is(unicode.Cf, r) || unicode.Is(unicode.Variation_Selector, r)
+}
+
+// Normalize is the first step of lenient decoding: it NFC-normalizes s,
+// trims surrounding space and drops invisible characters, noting what it
+// changed. Lookalike mapping is rune for rune, so the Index of a lenient
+// *DecodeError points into the string Normalize returns.
+func Normalize(s string) (string, []string) {
+	var notes []string
+	if n := norm.NFC.String(s); n != s {
+		notes = append(notes, "normalized to NFC")
+		s = n
+	}
+	s = strings.TrimSpace(s)
+	var b strings.Builder
+	var dropped tally
+	for _, r := range s {
+		if invisible(r) {
+			dropped.add(fmt.Sprintf("U+%04X", r))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	if dropped.n > 0 {
+		notes = append(notes, fmt.Sprintf("removed %d invisible character(s): %s", dropped.n, dropped))
+	}
+	return b.String(), notes
+}
+
+// tally counts notes in first-seen order, so a change repeated across an ID
+// is reported once.
+type tally struct {
+	n      int
+	keys   []string
+	counts map[string]int
+}
+
+func (t *tally) add(k string) {
+	if t.counts == nil {
+		t.counts = map[string]int{}
+	}
+	if t.counts[k] == 0 {
+		t.keys = append(t.keys, k)
+	}
+	t.counts[k]++
+	t.n++
+}
+
+// String renders the tally as "a ×3, b".
+func (t tally) String() string {
+	s := make([]string, len(t.keys))
+	for i, k := range t.keys {
+		s[i] = k
+		if c := t.counts[k]; c > 1 {
+			s[i] += fmt.Sprintf(" ×%d", c)
+		}
+	}
+	return strings.Join(s, ", ")
+}
+
+// mapConfusables replaces runes a does not know with the canonical glyph
+// they are confused with, or with their NFKC form (fullwidth digits,
+// letters and dashes) when that is a single known rune.
+func mapConfusables(s string, a *Alphabet) (string, []string) {
+	var read tally
+	known := func(r rune) bool {
+		if a == nil {
+			return r < 0x80
+		}
+		_, core := a.coreVal[r]
+		_, pre := a.prefixOf[r]
+		return core || pre || r == '-'
+	}
+	rs := []rune(s)
+	for i, r := range rs {
+		if known(r) {
+			continue
+		}
+		c, ok := confusables[r]
+		if !ok || !known(c) {
+			k := []rune(norm.NFKC.String(string(r)))
+			if len(k) != 1 || !known(k[0]) {
+				continue
+			}
+			c = k[0]
+		}
+		read.add(fmt.Sprintf("%q as %q", r, c))
+		rs[i] = c
+	}
+	if read.n == 0 {
+		return s, nil
+	}
+	return string(rs), []string{"read " + read.String()}
+}
+
+// ParseLenient is ParseWith for IDs that have been through chat apps, PDFs
+// or emoji fonts. It normalizes the input to NFC, drops invisible characters
+// such as variation selectors and zero-width joiners, and reads lookalikes
+// of glyphs (and of '-' and ASCII characters) as the canonical ones. Second
+// 60 in ASCII input is handled per leap. The notes say what was changed. An
+// unknown glyph that remains is reported as a *DecodeError with suggestions;
+// its Index counts runes of Normalize(s).
+func ParseLenient(s string, prefer *Alphabet, leap LeapSecondPolicy) (ID, []string, error) {
+	base, notes := Normalize(s)
+	if c, n := mapConfusables(base, nil); isASCII(c) {
+		id, err := parseASCII(c, prefer, leap)
+		return id, append(notes, n...), err
+	}
+	var firstErr error
+	var firstNotes []string
+	for _, a := range candidateAlphabets(prefer) {
+		c, n := mapConfusables(base, a)
+		id, err := parseVIZ(c, a)
+		if err == nil {
+			return id, append(notes, n...), nil
+		}
+		if firstErr == nil {
+			firstErr, firstNotes = err, n
+		}
+	}
+	return ID{}, append(notes, firstNotes...), firstErr
+}
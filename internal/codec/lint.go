@@ -0,0 +1,198 @@
+package codec
+
+import (
+	"fmt"
+	"unicode"
+
+	"golang.org/x/text/unicode/bidi"
+	"golang.org/x/text/unicode/norm"
+	"golang.org/x/text/width"
+)
+
+// LintRule is the outcome of one docs/requirements.md rule for a glyph table.
+type LintRule struct {
+	Rule        string   `json:"rule"`
+	Description string   `json:"description"`
+	Pass        bool     `json:"pass"`
+	Violations  []string `json:"violations,omitempty"`
+	// Waived is why a built-in alphabet is registered despite violating the
+	// rule; a waived rule does not fail the report.
+	Waived string `json:"waived,omitempty"`
+}
+
+// LintReport is the full audit of one glyph table.
+type LintReport struct {
+	Alphabet string     `json:"alphabet"`
+	Pass     bool       `json:"pass"`
+	Rules    []LintRule `json:"rules"`
+	// Legacy marks a superseded version, kept for decoding old IDs. Its
+	// violations are reported but do not fail `alphabet check --all`.
+	Legacy bool `json:"legacy,omitempty"`
+}
+
+// LintAlphabet audits a registered alphabet; the prefix table is taken in
+// PrefixSet order. Rules the alphabet waives are reported with the reason
+// and do not fail the report.
+func LintAlphabet(a *Alphabet) LintReport {
+	prefix := make([]rune, 0, len(PrefixSet))
+	for i := 0; i < len(PrefixSet); i++ {
+		prefix = append(prefix, a.prefix[PrefixSet[i]])
+	}
+	r := Lint(a.ID(), a.core, prefix)
+	r.Pass = true
+	for i := range r.Rules {
+		rule := &r.Rules[i]
+		if !rule.Pass {
+			rule.Waived = a.waivers[rule.Rule]
+			r.Pass = r.Pass && rule.Waived != ""
+		}
+	}
+	return r
+}
+
+// Lint audits raw core and prefix tables (prefix in PrefixSet order) against
+// the character set and sorting rules in docs/requirements.md. It never stops
+// at the first problem: every rule is evaluated and every violation listed.
+func Lint(name string, core, prefix []rune) LintReport {
+	type glyph struct {
+		where string
+		r     rune
+	}
+	var all []glyph
+	for i, r := range core {
+		all = append(all, glyph{fmt.Sprintf("core[%d]", i), r})
+	}
+	for i, r := range prefix {
+		all = append(all, glyph{fmt.Sprintf("prefix[%d]", i), r})
+	}
+	show := func(g glyph) string {
+		return fmt.Sprintf("%s %q (U+%04X)", g.where, string(g.r), g.r)
+	}
+	perGlyph := func(rule, desc string, bad func(rune) string) LintRule {
+		res := LintRule{Rule: rule, Description: desc}
+		for _, g := range all {
+			if why := bad(g.r); why != "" {
+				res.Violations = append(res.Violations, show(g)+" "+why)
+			}
+		}
+		return res
+	}
+
+	var rules []LintRule
+
+	size := LintRule{Rule: "size", Description: fmt.Sprintf("core has 36 glyphs, prefix has %d", len(PrefixSet))}
+	if len(core) != 36 {
+		size.Violations = append(size.Violations, fmt.Sprintf("core has %d glyphs", len(core)))
+	}
+	if len(prefix) != len(PrefixSet) {
+		size.Violations = append(size.Violations, fmt.Sprintf("prefix has %d glyphs", len(prefix)))
+	}
+	rules = append(rules, size)
+
+	distinct := LintRule{Rule: "distinct", Description: "every glyph appears once within its table"}
+	for _, t := range []struct {
+		table  string
+		glyphs []rune
+	}{{"core", core}, {"prefix", prefix}} {
+		seen := map[rune]int{}
+		for i, r := range t.glyphs {
+			if j, dup := seen[r]; dup {
+				distinct.Violations = append(distinct.Violations,
+					fmt.Sprintf("%s[%d] %q (U+%04X) duplicates %s[%d]", t.table, i, string(r), r, t.table, j))
+				continue
+			}
+			seen[r] = i
+		}
+	}
+	rules = append(rules, distinct)
+
+	ordered := LintRule{Rule: "ordered", Description: "code points strictly increase with value (prefix: with ASCII byte order)"}
+	for _, t := range []struct {
+		table  string
+		glyphs []rune
+	}{{"core", core}, {"prefix", prefix}} {
+		for _, i := range unorderedPairs(t.glyphs) {
+			ordered.Violations = append(ordered.Violations,
+				fmt.Sprintf("%s[%d] %q (U+%04X) does not sort after %s[%d] %q (U+%04X)",
+					t.table, i, string(t.glyphs[i]), t.glyphs[i], t.table, i-1, string(t.glyphs[i-1]), t.glyphs[i-1]))
+		}
+	}
+	rules = append(rules, ordered)
+
+	disjoint := LintRule{Rule: "disjoint", Description: "no glyph is in both the core and prefix sets"}
+	inCore := map[rune]int{}
+	for i, r := range core {
+		inCore[r] = i
+	}
+	for i, r := range prefix {
+		if j, ok := inCore[r]; ok {
+			disjoint.Violations = append(disjoint.Violations,
+				fmt.Sprintf("prefix[%d] %q (U+%04X) is also core[%d]", i, string(r), r, j))
+		}
+	}
+	rules = append(rules, disjoint)
+
+	rules = append(rules, perGlyph("combining", "no combining marks", func(r rune) string {
+		if unicode.Is(unicode.M, r) {
+			return "is a combining mark"
+		}
+		return ""
+	}))
+
+	rules = append(rules, perGlyph("bidi", "no bidi controls or right-to-left characters", func(r rune) string {
+		if unicode.Is(unicode.Bidi_Control, r) {
+			return "is a bidi control"
+		}
+		p, _ := bidi.LookupRune(r)
+		switch p.Class() {
+		case bidi.R, bidi.AL:
+			return "is right-to-left"
+		}
+		return ""
+	}))
+
+	rules = append(rules, perGlyph("emoji", "no emoji-capable characters or variation selectors", func(r rune) string {
+		if unicode.Is(unicode.Variation_Selector, r) {
+			return "is a variation selector"
+		}
+		if unicode.Is(emojiPresentation, r) {
+			return "has emoji presentation"
+		}
+		if unicode.Is(extendedPictographic, r) {
+			return "can render as emoji (Extended_Pictographic)"
+		}
+		return ""
+	}))
+
+	rules = append(rules, perGlyph("width", "East Asian Width is not wide or fullwidth", func(r rune) string {
+		switch width.LookupRune(r).Kind() {
+		case width.EastAsianWide:
+			return "is East Asian wide"
+		case width.EastAsianFullwidth:
+			return "is East Asian fullwidth"
+		}
+		return ""
+	}))
+
+	rules = append(rules, perGlyph("normalization", "unchanged by NFC, NFD and NFKC", func(r rune) string {
+		s := string(r)
+		for _, f := range []struct {
+			name string
+			form norm.Form
+		}{{"NFC", norm.NFC}, {"NFD", norm.NFD}, {"NFKC", norm.NFKC}} {
+			if f.form.String(s) != s {
+				return "changes under " + f.name
+			}
+		}
+		return ""
+	}))
+
+	report := LintReport{Alphabet: name, Pass: true, Rules: rules}
+	for i := range report.Rules {
+		report.Rules[i].Pass = len(report.Rules[i].Violations) == 0
+		if !report.Rules[i].Pass {
+			report.Pass = false
+		}
+	}
+	return report
+}
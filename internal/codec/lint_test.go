@@ -0,0 +1,90 @@
+package codec
+
+import "testing"
+
+func TestLint(t *testing.T) {
+	prefix := []rune{'ᛮ', 'ᛯ', 'ᛰ', 'ᛱ', 'ᛲ', 'ᛳ', 'ᛴ', 'ᛵ'}
+	core := func(edit func(c []rune) []rune) []rune {
+		c := append([]rune(nil), RunesCore36Glyphs...)
+		if edit == nil {
+			return c
+		}
+		return edit(c)
+	}
+	set := func(i int, r rune) func([]rune) []rune {
+		return func(c []rune) []rune { c[i] = r; return c }
+	}
+
+	tests := []struct {
+		name   string
+		core   []rune
+		prefix []rune
+		want   []string // the rules that fail
+	}{
+		{name: "clean", core: core(nil), prefix: prefix},
+		{name: "size", core: core(func(c []rune) []rune { return c[:35] }), prefix: prefix, want: []string{"size"}},
+		{name: "distinct", core: core(set(5, RunesCore36Glyphs[4])), prefix: prefix, want: []string{"distinct", "ordered"}},
+		{name: "ordered", core: core(func(c []rune) []rune { c[3], c[4] = c[4], c[3]; return c }), prefix: prefix, want: []string{"ordered"}},
+		{name: "disjoint", core: core(nil), prefix: append([]rune{RunesCore36Glyphs[0]}, prefix[1:]...), want: []string{"disjoint"}},
+		{name: "combining", core: core(set(0, '\u0301')), prefix: prefix, want: []string{"combining"}},
+		{name: "bidi", core: core(set(0, 'א')), prefix: prefix, want: []string{"bidi"}},
+		{name: "emoji", core: core(set(35, '☀')), prefix: prefix, want: []string{"emoji"}},
+		{name: "variation selector", core: core(set(35, '\uFE0F')), prefix: prefix, want: []string{"combining", "emoji"}},
+		{name: "width", core: core(set(35, '一')), prefix: prefix, want: []string{"width"}},
+		{name: "normalization", core: core(set(35, '\u212B')), prefix: prefix, want: []string{"normalization"}}, // ANGSTROM SIGN
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := Lint(tt.name, tt.core, tt.prefix)
+			var failed []string
+			for _, rule := range r.Rules {
+				if rule.Pass != (len(rule.Violations) == 0) {
+					t.Errorf("rule %s: Pass %v with %d violations", rule.Rule, rule.Pass, len(rule.Violations))
+				}
+				if !rule.Pass {
+					failed = append(failed, rule.Rule)
+				}
+			}
+			if !equalRules(failed, tt.want) {
+				t.Errorf("failed rules %q, want %q", failed, tt.want)
+			}
+			if r.Pass != (len(tt.want) == 0) {
+				t.Errorf("Pass = %v, want %v", r.Pass, len(tt.want) == 0)
+			}
+		})
+	}
+}
+
+func equalRules(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+func TestLintAlphabetWaiver(t *testing.T) {
+	r := LintAlphabet(Chess)
+	if !r.Pass {
+		t.Fatal("chess fails despite its waiver")
+	}
+	for _, rule := range r.Rules {
+		switch {
+		case rule.Rule == "emoji" && (rule.Pass || rule.Waived == ""):
+			t.Errorf("emoji: Pass %v, waived %q; want a waived failure", rule.Pass, rule.Waived)
+		case rule.Rule != "emoji" && !rule.Pass:
+			t.Errorf("%s fails: %q", rule.Rule, rule.Violations)
+		}
+	}
+	for _, a := range Alphabets() {
+		if a == Legacy || a == Chess {
+			continue
+		}
+		if r := LintAlphabet(a); !r.Pass {
+			t.Errorf("%s fails: %+v", a.ID(), r.Rules)
+		}
+	}
+}
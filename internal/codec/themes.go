@@ -0,0 +1,147 @@
+package codec
+
+// Themed alphabets. Each one follows docs/requirements.md like the default
+// table: text-presentation (monochrome) glyphs that are not emoji-capable
+// (chess aside, see below), no combining marks, and both tables in strictly
+// increasing code point order so IDs still sort by time.
+// The themes share no glyphs with each other or with the geometric tables,
+// which lets decoding detect the alphabet from the glyphs alone.
+
+// FacesCore36Glyphs: APL faces, circled-dot eyes, Cyrillic eye letters,
+// Egyptian head/eye/ear/nose/mouth hieroglyphs (D-series), half-circle faces.
+// The Misc Symbols smileys (☹ ☺ ☻) are left out: they are emoji-capable.
+var FacesCore36Glyphs = []rune{
+	'⌢', '⌣', '⍢', '⍣', '⍤', '⍥', '⍨', '⍩', // U+2322..U+2369
+	'⚆', '⚇', '⚈', '⚉', // U+2686..U+2689
+	'Ꙩ', 'Ꙫ', 'Ꙭ', 'ꙮ', // U+A668..U+A66E
+	'𓁶', '𓁷', '𓁸', '𓁹', '𓁺', '𓁻', '𓁼', '𓁿', '𓂀', // U+13076..U+13080
+	'𓂇', '𓂈', '𓂉', '𓂊', '𓂋', '𓂌', '𓂎', '𓂏', '𓂐', // U+13087..U+13090
+	'🤅', '🤆', // U+1F905..U+1F906
+}
+
+// FacesPrefixASCII: hand, arm and foot hieroglyphs (D-series).
+var FacesPrefixASCII = map[byte]rune{
+	'!': '𓂧', // U+130A7
+	'$': '𓂪', // U+130AA
+	'%': '𓂬', // U+130AC
+	'&': '𓂭', // U+130AD
+	'*': '𓂻', // U+130BB
+	'@': '𓂾', // U+130BE
+	'^': '𓃀', // U+130C0
+	'~': '𓃃', // U+130C3
+}
+
+// AnimalsCore36Glyphs: Egyptian hieroglyphs for mammals (E), birds (G),
+// reptiles and amphibians (I), fish (K) and invertebrates (L).
+var AnimalsCore36Glyphs = []rune{
+	'𓃒', '𓃔', '𓃗', '𓃘', '𓃝', '𓃠', '𓃡', '𓃥', '𓃬', '𓃯', '𓃰', '𓃱', '𓃷', '𓃹', // mammals
+	'𓄿', '𓅃', '𓅓', '𓅙', '𓅝', '𓅟', '𓅣', '𓅨', '𓅬', '𓅷', // birds
+	'𓆈', '𓆊', '𓆏', '𓆑', '𓆓', // reptiles, amphibians
+	'𓆛', '𓆝', '𓆞', // fish
+	'𓆣', '𓆤', '𓆧', '𓆫', // invertebrates
+}
+
+// AnimalsPrefixASCII: heads, horns, wings, feathers and eggs (F/H-series).
+var AnimalsPrefixASCII = map[byte]rune{
+	'!': '𓃾', // U+130FE
+	'$': '𓄂', // U+13102
+	'%': '𓄅', // U+13105
+	'&': '𓄋', // U+1310B
+	'*': '𓄏', // U+1310F
+	'@': '𓆃', // U+13183
+	'^': '𓆄', // U+13184
+	'~': '𓆇', // U+13187
+}
+
+// WeatherCore36Glyphs: wave, snowflakes, small stars and moons, Egyptian
+// sky, sun, moon, star, horizon and water hieroglyphs (N-series), and the
+// alchemical elements. The Misc Symbols and Misc Pictographs weather glyphs
+// (☀ ☁ ❄ 🌧 ...) are left out: they are emoji-capable.
+var WeatherCore36Glyphs = []rune{
+	'∿', '❅', '❆', '⭑', '⭒', '⯝', '⯟', '⯪', '⯫', // U+223F..U+2BEB
+	'𓇯', '𓇰', '𓇱', '𓇲', '𓇳', '𓇴', '𓇵', '𓇶', '𓇷', // sky, sun: U+131EF..U+131F7
+	'𓇸', '𓇹', '𓇺', '𓇻', '𓇼', '𓇽', '𓈋', '𓈌', '𓈍', // moon, star, horizon: U+131F8..U+1320D
+	'𓈖', '𓈗', '𓈘', '𓈙', // water: U+13216..U+13219
+	'🜀', '🜁', '🜂', '🜃', '🜄', // U+1F700..U+1F704
+}
+
+// WeatherPrefixASCII: outlined and pinwheel stars.
+var WeatherPrefixASCII = map[byte]rune{
+	'!': '✩', // U+2729
+	'$': '✪', // U+272A
+	'%': '✫', // U+272B
+	'&': '✬', // U+272C
+	'*': '✭', // U+272D
+	'@': '✮', // U+272E
+	'^': '✯', // U+272F
+	'~': '✰', // U+2730
+}
+
+// RunesCore36Glyphs: Elder Futhark and Anglo-Saxon runes. ᛁ is left out as
+// it reads as a vertical bar.
+var RunesCore36Glyphs = []rune{
+	'ᚠ', 'ᚢ', 'ᚣ', 'ᚦ', 'ᚨ', 'ᚩ', 'ᚪ', 'ᚫ', 'ᚱ', // U+16A0..U+16B1
+	'ᚲ', 'ᚳ', 'ᚷ', 'ᚸ', 'ᚹ', 'ᚺ', 'ᚾ', 'ᛃ', 'ᛄ', // U+16B2..U+16C4
+	'ᛇ', 'ᛈ', 'ᛉ', 'ᛊ', 'ᛏ', 'ᛒ', 'ᛖ', 'ᛗ', 'ᛚ', // U+16C7..U+16DA
+	'ᛜ', 'ᛝ', 'ᛞ', 'ᛟ', 'ᛠ', 'ᛡ', 'ᛣ', 'ᛤ', 'ᛥ', // U+16DC..U+16E5
+}
+
+// RunesPrefixASCII: golden number runes, then the Tolkien and Franks Casket
+// runes.
+var RunesPrefixASCII = map[byte]rune{
+	'!': 'ᛮ', // U+16EE
+	'$': 'ᛯ', // U+16EF
+	'%': 'ᛰ', // U+16F0
+	'&': 'ᛱ', // U+16F1
+	'*': 'ᛲ', // U+16F2
+	'@': 'ᛳ', // U+16F3
+	'^': 'ᛴ', // U+16F4
+	'~': 'ᛵ', // U+16F5
+}
+
+// ChessCore36Glyphs: white, black and neutral pieces, turned pieces and the
+// knight-compound fairy pieces.
+var ChessCore36Glyphs = []rune{
+	'♔', '♕', '♖', '♗', '♘', '♙', // white
+	'♚', '♛', '♜', '♝', '♞', '♟', // black
+	'🨀', '🨁', '🨂', '🨃', '🨄', '🨅', // neutral
+	'🨞', '🨟', '🨠', '🨡', '🨢', '🨣', // white turned
+	'🨤', '🨥', '🨦', '🨧', '🨨', '🨩', // black turned
+	'🩎', '🩏', '🩐', '🩑', '🩒', '🩓', // knight compounds
+}
+
+// ChessPrefixASCII: shogi and draughts pieces.
+var ChessPrefixASCII = map[byte]rune{
+	'!': '☖', // U+2616
+	'$': '☗', // U+2617
+	'%': '⛀', // U+26C0
+	'&': '⛁', // U+26C1
+	'*': '⛂', // U+26C2
+	'@': '⛃', // U+26C3
+	'^': '⛉', // U+26C9
+	'~': '⛊', // U+26CA
+}
+
+// chessWaiver is why chess is registered although it fails the emoji rule:
+// only ♟ is Emoji=Yes, but every chess, shogi and draughts piece is
+// Extended_Pictographic.
+const chessWaiver = "every piece is Extended_Pictographic and ♟ is Emoji=Yes, so some fonts may draw them as emoji; runes is the theme that passes"
+
+var (
+	Faces   = mustAlphabet("faces", 1, FacesCore36Glyphs, FacesPrefixASCII)
+	Animals = mustAlphabet("animals", 1, AnimalsCore36Glyphs, AnimalsPrefixASCII)
+	Weather = mustAlphabet("weather", 1, WeatherCore36Glyphs, WeatherPrefixASCII)
+	Runes   = mustAlphabet("runes", 1, RunesCore36Glyphs, RunesPrefixASCII)
+	Chess   = mustAlphabet("chess", 1, ChessCore36Glyphs, ChessPrefixASCII).waive("emoji", chessWaiver)
+)
+
+func init() {
+	for _, a := range []*Alphabet{Faces, Animals, Weather, Runes, Chess} {
+		if err := VerifyOrdered(a.core, a.prefix); err != nil {
+			panic("codec: alphabet " + a.ID() + ": " + err.Error())
+		}
+		if err := Register(a); err != nil {
+			panic("codec: " + err.Error())
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package codec
+
+import (
+	"fmt"
+	"sort"
+)
+
+// VerifyOrdered checks that a glyph table preserves sort order:
+//   - core has exactly 36 distinct glyphs with strictly increasing code points
+//   - prefix glyphs have strictly increasing code points in ASCII byte order
+//   - no glyph appears in both the core and prefix sets
+func VerifyOrdered(core []rune, prefix map[byte]rune) error {
+	if len(core) != 36 {
+		return fmt.Errorf("core table has %d glyphs, want 36", len(core))
+	}
+	for i := 1; i < len(core); i++ {
+		if core[i] <= core[i-1] {
+			return fmt.Errorf("core glyph %d %q (U+%04X) does not sort after value %d %q (U+%04X)",
+				i, string(core[i]), core[i], i-1, string(core[i-1]), core[i-1])
+		}
+	}
+
+	keys := make([]byte, 0, len(prefix))
+	for k := range prefix {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	for i := 1; i < len(keys); i++ {
+		a, b := prefix[keys[i-1]], prefix[keys[i]]
+		if b <= a {
+			return fmt.Errorf("prefix glyph for %q %q (U+%04X) does not sort after %q %q (U+%04X)",
+				keys[i], string(b), b, keys[i-1], string(a), a)
+		}
+	}
+
+	inCore := map[rune]bool{}
+	for _, r := range core {
+		inCore[r] = true
+	}
+	for _, k := range keys {
+		if inCore[prefix[k]] {
+			return fmt.Errorf("prefix glyph %q for %q is also a core glyph", string(prefix[k]), k)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package codec
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVerifyOrdered(t *testing.T) {
+	core := func() []rune { return append([]rune(nil), Core36Glyphs...) }
+	prefix := func() map[byte]rune {
+		m := map[byte]rune{}
+		for k, v := range PrefixASCII {
+			m[k] = v
+		}
+		return m
+	}
+	swapped := core()
+	swapped[3], swapped[4] = swapped[4], swapped[3]
+	repeated := core()
+	repeated[5] = repeated[4]
+	badPrefix := prefix()
+	badPrefix['!'], badPrefix['~'] = badPrefix['~'], badPrefix['!']
+	shared := map[byte]rune{} // ordered, but the last 8 core glyphs
+	for i := 0; i < len(PrefixSet); i++ {
+		shared[PrefixSet[i]] = Core36Glyphs[28+i]
+	}
+
+	tests := []struct {
+		name    string
+		core    []rune
+		prefix  map[byte]rune
+		wantErr string // "" for none
+	}{
+		{name: "default", core: core(), prefix: prefix()},
+		{name: "short core", core: core()[:35], prefix: prefix(), wantErr: "35 glyphs"},
+		{name: "unordered core", core: swapped, prefix: prefix(), wantErr: "core glyph 4"},
+		{name: "repeated core glyph", core: repeated, prefix: prefix(), wantErr: "core glyph 5"},
+		{name: "unordered prefix", core: core(), prefix: badPrefix, wantErr: "prefix glyph"},
+		{name: "prefix glyph in core", core: core(), prefix: shared, wantErr: "also a core glyph"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyOrdered(tt.core, tt.prefix)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatal(err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("got %v, want an error mentioning %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	// the legacy table is why the rule exists
+	if err := VerifyOrdered(LegacyCore36Glyphs, LegacyPrefixASCII); err == nil {
+		t.Error("the legacy v1 table passes")
+	}
+}
@@ -0,0 +1,83 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/ryanl/vizid/internal/model"
+)
+
+// benchOptions uses the borrow overflow policy without a drift limit, so the
+// benchmarks measure the generator rather than the 1296-per-millisecond
+// counter limit.
+func benchOptions() model.Options {
+	opts := testOptions()
+	opts.Overflow = model.OverflowBorrow
+	return opts
+}
+
+// BenchmarkGenerate is the package-level Generate, which reuses one cached
+// generator per option set and shares one process-wide counter state.
+func BenchmarkGenerate(b *testing.B) {
+	opts := benchOptions()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, _, _, err := Generate(opts, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkNext is a reused Generator rendering into a reused buffer.
+func BenchmarkNext(b *testing.B) {
+	g, err := New(benchOptions())
+	if err != nil {
+		b.Fatal(err)
+	}
+	ctx := context.Background()
+	buf := make([]byte, 0, 64)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		id, _, err := g.Next(ctx)
+		if err != nil {
+			b.Fatal(err)
+		}
+		buf = id.AppendVIZ(buf[:0])
+	}
+}
+
+// BenchmarkNextParallel is Next from all cores, per shard count up to
+// GOMAXPROCS.
+func BenchmarkNextParallel(b *testing.B) {
+	for shards := 1; ; shards *= 2 {
+		if shards > MaxShards {
+			break
+		}
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			g, err := New(benchOptions(), WithShards(shards))
+			if err != nil {
+				b.Fatal(err)
+			}
+			ctx := context.Background()
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				buf := make([]byte, 0, 64)
+				for pb.Next() {
+					id, _, err := g.Next(ctx)
+					if err != nil {
+						b.Error(err)
+						return
+					}
+					buf = id.AppendVIZ(buf[:0])
+				}
+			})
+		})
+		if shards >= runtime.GOMAXPROCS(0) {
+			break
+		}
+	}
+}
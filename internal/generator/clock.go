@@ -0,0 +1,89 @@
+package generator
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Clock is the generator's source of wall-clock time. Sleep is used while
+// waiting for the next millisecond, so a fake clock can advance instead of
+// blocking.
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+}
+
+// ContextSleeper is implemented by clocks whose Sleep can be cut short.
+// Generators use it, when available, to give up waiting as soon as the
+// caller's context is done; other clocks are only checked between sleeps.
+type ContextSleeper interface {
+	SleepContext(ctx context.Context, d time.Duration) error
+}
+
+// SystemClock is the real wall clock.
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time        { return time.Now() }
+func (SystemClock) Sleep(d time.Duration) { time.Sleep(d) }
+
+// SleepContext sleeps for d or until ctx is done, returning ctx.Err() in
+// the latter case.
+func (SystemClock) SleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// FakeClock is a deterministic Clock for tests. Time only moves when Set,
+// Advance or Sleep is called, or by Step after every Now.
+type FakeClock struct {
+	mu   sync.Mutex
+	now  time.Time
+	step time.Duration
+}
+
+// NewFakeClock returns a FakeClock reading t.
+func NewFakeClock(t time.Time) *FakeClock {
+	return &FakeClock{now: t}
+}
+
+// Now returns the current fake time, then advances it by the step (if any).
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := c.now
+	c.now = c.now.Add(c.step)
+	return t
+}
+
+// Sleep advances the fake time by d without blocking.
+func (c *FakeClock) Sleep(d time.Duration) {
+	c.Advance(d)
+}
+
+// Advance moves the fake time forward (or backward, for negative d).
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+// Set jumps the fake time to t.
+func (c *FakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	c.now = t
+	c.mu.Unlock()
+}
+
+// SetStep makes every Now call advance the time by d afterwards.
+func (c *FakeClock) SetStep(d time.Duration) {
+	c.mu.Lock()
+	c.step = d
+	c.mu.Unlock()
+}
@@ -0,0 +1,62 @@
+package generator
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ryanl/vizid/internal/codec"
+	"github.com/ryanl/vizid/internal/model"
+	"github.com/ryanl/vizid/internal/timeutil"
+)
+
+// contentSpace is the number of TTCCR values: five base-36 digits.
+const contentSpace = 36 * 36 * 36 * 36 * 36
+
+// ContentHash hashes a namespace and content into a TTCCR value
+// (0..36^5-1): SHA-256 over the namespace, a zero byte and the content, with
+// the first 8 bytes of the digest reduced modulo 36^5. Like a UUIDv5, the
+// same namespace and content always give the same value.
+func ContentHash(namespace string, content io.Reader) (int, error) {
+	h := sha256.New()
+	h.Write([]byte(namespace))
+	h.Write([]byte{0})
+	if _, err := io.Copy(h, content); err != nil {
+		return 0, err
+	}
+	sum := h.Sum(nil)
+	return int(binary.BigEndian.Uint64(sum[:8]) % contentSpace), nil
+}
+
+// FromContent builds the content-derived ID for content: the sortable
+// timestamp of t (in opts.Timezone, with opts.Components) and, after
+// codec.ContentPrefix, the ContentHash of namespace and content as TTCCR,
+// closed by codec.ContentMarker. It involves no generator state, so the same
+// inputs always give the same ID.
+func FromContent(opts model.Options, alpha *codec.Alphabet, t time.Time, namespace string, content io.Reader) (codec.ID, error) {
+	if !opts.Components.UUID {
+		return codec.ID{}, fmt.Errorf("content-derived IDs need the uuid component")
+	}
+	loc, err := timeutil.LoadLocation(opts.Timezone)
+	if err != nil {
+		return codec.ID{}, err
+	}
+	ts, err := encodeTimestamp(t.In(loc), opts.Components)
+	if err != nil {
+		return codec.ID{}, err
+	}
+	h, err := ContentHash(namespace, content)
+	if err != nil {
+		return codec.ID{}, fmt.Errorf("hash content: %w", err)
+	}
+	uuid := codec.UUID{
+		Prefix:  codec.ContentPrefix,
+		TimeMix: h / (1296 * 36),
+		Counter: h / 36 % 1296,
+		Salt:    h % 36,
+		Content: true,
+	}
+	return codec.NewID(ts, &uuid, alpha, loc)
+}
@@ -0,0 +1,56 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ryanl/vizid/internal/codec"
+)
+
+func TestFromContent(t *testing.T) {
+	mint := func(namespace, content string) codec.ID {
+		t.Helper()
+		id, err := FromContent(testOptions(), nil, t0, namespace, strings.NewReader(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+	a, b := mint("notes", "hello"), mint("notes", "hello")
+	if a.ASCII() != b.ASCII() {
+		t.Errorf("same inputs gave %s and %s", a.ASCII(), b.ASCII())
+	}
+	if !a.IsContent() || !strings.HasSuffix(a.ASCII(), "C") || !a.Time().Equal(t0) {
+		t.Errorf("%s: want a content-derived ~TTCCRC ID at %s", a.ASCII(), t0.Format("2006-01-02T15:04:05.000Z07:00"))
+	}
+	h, err := ContentHash("notes", strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := a.ContentHash(); !ok || got != h {
+		t.Errorf("ContentHash() = %d, %v; want %d", got, ok, h)
+	}
+	back, err := codec.Parse(a.VIZ())
+	if err != nil || back.ASCII() != a.ASCII() || !back.IsContent() {
+		t.Errorf("VIZ round trip: got %s (content %v), %v", back.ASCII(), back.IsContent(), err)
+	}
+
+	// the namespace and the content both select the ID
+	for _, other := range []codec.ID{mint("photos", "hello"), mint("", "hello"), mint("notes", "hello!")} {
+		if other.ASCII() == a.ASCII() {
+			t.Errorf("distinct inputs both gave %s", a.ASCII())
+		}
+	}
+	// the zero byte separates the namespace from the content
+	if mint("ab", "c").ASCII() == mint("a", "bc").ASCII() {
+		t.Error("namespace ab with content c and namespace a with content bc collide")
+	}
+}
+
+func TestFromContentNeedsUUID(t *testing.T) {
+	opts := testOptions()
+	opts.Components.UUID = false
+	if _, err := FromContent(opts, nil, t0, "", strings.NewReader("x")); err == nil {
+		t.Error("want an error without the uuid component")
+	}
+}
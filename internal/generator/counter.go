@@ -0,0 +1,377 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ryanl/vizid/internal/model"
+)
+
+// MaxShards is the most shards a Generator may split its counter into.
+const MaxShards = 64
+
+// state is one shard's monotonic counter state: the last millisecond issued
+// and the counter within it. The shard owns the counter values offset,
+// offset+stride, ... of every millisecond (its lane); an unsharded
+// generator has one shard with offset 0 and stride 1.
+type state struct {
+	mu      sync.Mutex
+	lastMs  int64 // last millisecond stamped; ahead of wallMs after borrowing
+	counter int
+	wallMs  int64 // latest wall-clock millisecond seen
+
+	// peak is the highest counter the shard has issued in any millisecond
+	// and readMs the previous clock reading, for ClockWarn: a regressed
+	// millisecond is restamped with counters above peak so it never repeats
+	// an ID, and the warning is given once per step back.
+	peak   int
+	readMs int64
+
+	offset, stride int
+
+	cache tsEntry // timestamp of the latest millisecond stamped
+
+	borrows  uint64
+	maxDrift int64
+}
+
+// DriftStats reports how far borrowing has moved a generator's logical clock
+// ahead of the wall clock.
+type DriftStats struct {
+	Current time.Duration // logical time minus the latest wall-clock reading
+	Max     time.Duration // largest drift seen
+	Borrows uint64        // milliseconds borrowed so far
+}
+
+func newShards(n int) []*state {
+	shards := make([]*state, n)
+	for i := range shards {
+		shards[i] = &state{offset: i, stride: n, counter: i, peak: i - n, cache: tsEntry{ms: -1}}
+	}
+	return shards
+}
+
+// laneSize is the number of counter values a shard owns per millisecond.
+func (g *Generator) laneSize(offset, stride int) int {
+	return (g.span-1-offset)/stride + 1
+}
+
+// pick chooses a shard: round-robin, moving on past shards that are busy
+// so callers spread out, and queueing on the first choice only when every
+// shard is taken. It returns the shard unlocked.
+func (g *Generator) pick() *state {
+	if len(g.shards) == 1 {
+		return g.shards[0]
+	}
+	n := uint32(len(g.shards))
+	start := g.next.Add(1)
+	for i := uint32(0); i < n; i++ {
+		st := g.shards[(start+i)%n]
+		if st.mu.TryLock() {
+			st.mu.Unlock()
+			return st
+		}
+	}
+	return g.shards[start%n]
+}
+
+// nextCounter is advance, synchronised through the state file when there is
+// one: the file stays locked from reading the stored state until the new
+// state is written back.
+func (g *Generator) nextCounter(ctx context.Context, st *state, n int) (int64, int, tsEntry, string, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, 0, tsEntry{}, "", err
+	}
+	if g.stateFile == nil {
+		return g.advance(ctx, st, n)
+	}
+	fh, err := g.stateFile.acquire()
+	if err != nil {
+		return 0, 0, tsEntry{}, "", err
+	}
+	defer g.stateFile.release(fh)
+	stored, err := g.stateFile.read(fh)
+	if err != nil {
+		return 0, 0, tsEntry{}, "", err
+	}
+	st.mu.Lock()
+	st.merge(stored)
+	st.mu.Unlock()
+	g.hist.mu.Lock()
+	g.hist.merge(stored)
+	g.hist.mu.Unlock()
+
+	ms, cc, ts, warn, err := g.advance(ctx, st, n)
+	if err != nil {
+		return 0, 0, tsEntry{}, "", err
+	}
+	st.mu.Lock()
+	g.hist.mu.Lock()
+	stored = st.stored(g.hist)
+	g.hist.mu.Unlock()
+	st.mu.Unlock()
+	if err := g.stateFile.write(fh, stored); err != nil {
+		return 0, 0, tsEntry{}, "", err
+	}
+	return ms, cc, ts, warn, nil
+}
+
+// merge takes in the state another process stored, keeping whichever of
+// it and st is further along. Called with st.mu held.
+func (st *state) merge(stored storedState) {
+	if stored.lastMs > st.lastMs || (stored.lastMs == st.lastMs && stored.counter > st.counter) {
+		st.lastMs, st.counter = stored.lastMs, stored.counter
+	}
+	if stored.wallMs > st.wallMs {
+		st.wallMs = stored.wallMs
+	}
+	if stored.peak > st.peak {
+		st.peak = stored.peak
+	}
+}
+
+// stored returns st, with the explicit instants' counters and the first
+// live millisecond from h, as the state file holds it. Called with st.mu
+// and h.mu held.
+func (st *state) stored(h *history) storedState {
+	live := h.liveFrom.Load()
+	if live == noLive {
+		live = 0
+	}
+	return storedState{
+		lastMs: st.lastMs, counter: st.counter, wallMs: st.wallMs, peak: st.peak, liveFrom: live,
+		at: h.next, uptoMs: h.uptoMs, uptoNext: h.uptoNext,
+	}
+}
+
+// advance claims n counter values from shard st and returns the millisecond
+// and counter of the first, with that millisecond's timestamp; the rest
+// follow it through the shard's lane, spilling into later milliseconds.
+func (g *Generator) advance(ctx context.Context, st *state, n int) (int64, int, tsEntry, string, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	ms, cc, warn, err := g.advanceLocked(ctx, st, n)
+	if err != nil {
+		return 0, 0, tsEntry{}, "", err
+	}
+	if st.cache.ms != ms {
+		st.cache = g.timestamp(ms)
+	}
+	ts := st.cache
+	switch {
+	case ms < st.lastMs:
+		// a restamped regressed millisecond; the block fits in its lane
+		st.peak = cc + (n-1)*st.stride
+	case n > 1:
+		per := g.laneSize(st.offset, st.stride)
+		end := (cc-st.offset)/st.stride + n - 1
+		st.lastMs = ms + int64(end/per)
+		st.counter = st.offset + (end%per)*st.stride
+		st.borrows += uint64(end / per)
+		if end >= per {
+			st.peak = st.offset + (per-1)*st.stride
+		}
+		// the block is claimed, so callers waiting meanwhile start after it
+		if err := g.awaitDrift(ctx, st); err != nil {
+			return 0, 0, tsEntry{}, "", err
+		}
+		if d := st.lastMs - st.wallMs; d > st.maxDrift {
+			st.maxDrift = d
+		}
+	}
+	if st.counter > st.peak {
+		st.peak = st.counter
+	}
+	return ms, cc, ts, warn, nil
+}
+
+// advanceLocked reads the clock and returns the millisecond to stamp and the
+// counter (monotonic within that millisecond). A clock that has stepped
+// behind the latest reading is handled per the clock policy, and counter
+// overflow per the overflow policy; the string is a warning for the warn
+// clock policy. n is the number of values the caller claims, so ClockWarn
+// only restamps a regressed millisecond with room for all of them. Called
+// with st.mu held.
+func (g *Generator) advanceLocked(ctx context.Context, st *state, n int) (int64, int, string, error) {
+	ms := g.clock.Now().UnixMilli()
+	prev := st.readMs
+	st.readMs = ms
+	warn := ""
+	if ms < st.wallMs {
+		switch g.opts.ClockPolicy {
+		case model.ClockWait:
+			for ms < st.wallMs {
+				if err := g.sleepUnlocked(ctx, st, time.Duration(st.wallMs-ms)*time.Millisecond); err != nil {
+					return 0, 0, "", err
+				}
+				ms = g.clock.Now().UnixMilli()
+			}
+		case model.ClockLogical:
+			ms = st.wallMs
+		case model.ClockWarn:
+			if ms < prev {
+				warn = fmt.Sprintf("clock moved backwards by %dms; IDs may sort before ones already issued", prev-ms)
+			}
+			if cc, ok := g.restamp(st, ms, n); ok {
+				return ms, cc, warn, nil
+			}
+			// no room left in ms: carry on in the last millisecond, as
+			// logical does
+			ms = st.wallMs
+		default:
+			return 0, 0, "", fmt.Errorf("clock moved backwards: %d < %d", ms, st.wallMs)
+		}
+	}
+	st.wallMs = ms
+	g.hist.markLive(ms)
+	if ms > st.lastMs {
+		if g.enter(st, ms) {
+			return ms, st.counter, warn, nil
+		}
+	} else {
+		st.counter += st.stride
+		if st.counter < g.span {
+			return st.lastMs, st.counter, warn, nil
+		}
+	}
+	if g.opts.ClockPolicy == model.ClockLogical || g.opts.Overflow == model.OverflowBorrow {
+		ms, err := g.borrow(ctx, st)
+		if err != nil {
+			return 0, 0, "", err
+		}
+		return ms, st.counter, warn, nil
+	}
+	// block/spin until next millisecond tick
+	for {
+		if err := g.sleepUnlocked(ctx, st, time.Microsecond*200); err != nil {
+			return 0, 0, "", err
+		}
+		ms2 := g.clock.Now().UnixMilli()
+		if ms2 > st.lastMs {
+			st.wallMs = ms2
+			if g.enter(st, ms2) {
+				return ms2, st.counter, warn, nil
+			}
+		}
+	}
+}
+
+// enter moves the shard's counter into the later millisecond ms, starting
+// above any counters ReserveAt gave out there. It reports false when ms
+// has none left in the shard's lane. Called with st.mu held.
+func (g *Generator) enter(st *state, ms int64) bool {
+	st.lastMs = ms
+	st.counter = g.hist.liveStart(ms, st.offset, st.stride)
+	return st.counter < g.span
+}
+
+// restamp claims n lane values of the regressed millisecond ms for
+// ClockWarn. Earlier IDs may hold any counter up to the shard's peak in ms,
+// and explicit instants (ReserveAt) those below the history's next free
+// one, so the values start above both. It reports false when they do not
+// fit in ms. Called with st.mu held.
+func (g *Generator) restamp(st *state, ms int64, n int) (int, bool) {
+	h := g.hist
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	first := st.peak + st.stride
+	if next := h.used(ms); next > first {
+		first = st.offset + (next-st.offset+st.stride-1)/st.stride*st.stride
+	}
+	last := first + (n-1)*st.stride
+	if last >= g.span {
+		return 0, false
+	}
+	if last >= h.used(ms) {
+		h.next[ms] = last + 1
+		h.prune()
+	}
+	return first, true
+}
+
+// borrow moves the logical clock one millisecond ahead instead of waiting
+// for the wall clock, first waiting only as long as needed to stay within
+// MaxDrift. Called with st.mu held.
+func (g *Generator) borrow(ctx context.Context, st *state) (int64, error) {
+	if max := g.opts.MaxDrift.Milliseconds(); max > 0 {
+		for st.lastMs+1-st.wallMs > max {
+			if err := g.sleepUnlocked(ctx, st, time.Duration(st.lastMs+1-st.wallMs-max)*time.Millisecond); err != nil {
+				return 0, err
+			}
+			if ms := g.clock.Now().UnixMilli(); ms > st.wallMs {
+				st.wallMs = ms
+			}
+		}
+	}
+	ms := st.lastMs + 1
+	if st.wallMs > st.lastMs {
+		// the wall clock caught up while we waited
+		ms = st.wallMs
+	} else {
+		st.borrows++
+	}
+	if d := ms - st.wallMs; d > st.maxDrift {
+		st.maxDrift = d
+	}
+	if !g.enter(st, ms) {
+		return g.borrow(ctx, st)
+	}
+	return ms, nil
+}
+
+// awaitDrift waits until the shard's logical clock is within MaxDrift of
+// the wall clock, after a block has spilled past it. Called with st.mu held.
+func (g *Generator) awaitDrift(ctx context.Context, st *state) error {
+	max := g.opts.MaxDrift.Milliseconds()
+	if max <= 0 {
+		return nil
+	}
+	for st.lastMs-st.wallMs > max {
+		if err := g.sleepUnlocked(ctx, st, time.Duration(st.lastMs-st.wallMs-max)*time.Millisecond); err != nil {
+			return err
+		}
+		if ms := g.clock.Now().UnixMilli(); ms > st.wallMs {
+			st.wallMs = ms
+		}
+	}
+	return nil
+}
+
+// sleepUnlocked sleeps on the generator's clock with st.mu released, so
+// other callers are not blocked behind the sleeper. It returns ctx.Err()
+// once ctx is done.
+func (g *Generator) sleepUnlocked(ctx context.Context, st *state, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	st.mu.Unlock()
+	defer st.mu.Lock()
+	if cs, ok := g.clock.(ContextSleeper); ok {
+		return cs.SleepContext(ctx, d)
+	}
+	g.clock.Sleep(d)
+	return ctx.Err()
+}
+
+// Drift reports how far borrowing has moved the logical clock ahead of the
+// wall clock: the largest current and maximum drift of any shard, and the
+// milliseconds borrowed by all shards.
+func (g *Generator) Drift() DriftStats {
+	var d DriftStats
+	for _, st := range g.shards {
+		st.mu.Lock()
+		cur := time.Duration(st.lastMs-st.wallMs) * time.Millisecond
+		max := time.Duration(st.maxDrift) * time.Millisecond
+		if cur > d.Current {
+			d.Current = cur
+		}
+		if max > d.Max {
+			d.Max = max
+		}
+		d.Borrows += st.borrows
+		st.mu.Unlock()
+	}
+	return d
+}
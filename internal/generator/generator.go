@@ -1,9 +1,13 @@
 package generator
 
 import (
+	"context"
 	"crypto/rand"
 	"fmt"
+	"io"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ryanl/vizid/internal/codec"
@@ -11,226 +15,542 @@ import (
 	"github.com/ryanl/vizid/internal/timeutil"
 )
 
+// Generator mints IDs. Each Generator owns its monotonic counter state, so
+// independent instances never share or contend on it. Build one with New.
+type Generator struct {
+	opts    model.Options
+	loc     *time.Location
+	alpha   *codec.Alphabet
+	clock   Clock
+	entropy io.Reader
+
+	// node is drawn from entropy unless set; prefixSet and saltSet record
+	// which halves were given explicitly.
+	node      Node
+	prefixSet bool
+	saltSet   bool
+	mixer     Mixer
+	layout    codec.Layout
+	extended  bool // IDs are extended ^D... UUIDs: a non-classic layout
+	span      int  // counter values per millisecond
+
+	shards    []*state
+	next      atomic.Uint32 // round-robin shard cursor
+	hist      *history      // counters for explicit instants (NextAt)
+	stateFile *StateFile
+}
+
+// Option configures a Generator.
+type Option func(*Generator) error
+
+// WithClock sets the time source (default SystemClock).
+func WithClock(c Clock) Option {
+	return func(g *Generator) error {
+		if c == nil {
+			return fmt.Errorf("nil clock")
+		}
+		g.clock = c
+		return nil
+	}
+}
+
+// WithEntropy sets the randomness source (default crypto/rand.Reader). It
+// draws the node, and the random digits of layouts that have them; for
+// those it is read concurrently and must be safe for that.
+func WithEntropy(r io.Reader) Option {
+	return func(g *Generator) error {
+		if r == nil {
+			return fmt.Errorf("nil entropy source")
+		}
+		g.entropy = r
+		return nil
+	}
+}
+
+// WithNode fixes both the prefix and the salt digit.
+func WithNode(n Node) Option {
+	return func(g *Generator) error {
+		if err := n.validate(); err != nil {
+			return err
+		}
+		g.node = n
+		g.prefixSet, g.saltSet = true, true
+		return nil
+	}
+}
+
+// WithPrefix fixes the prefix P instead of drawing it from the entropy source.
+func WithPrefix(p byte) Option {
+	return func(g *Generator) error {
+		if err := (Node{Prefix: p}).validate(); err != nil {
+			return err
+		}
+		g.node.Prefix = p
+		g.prefixSet = true
+		return nil
+	}
+}
+
+// WithSalt fixes the salt digit R (0..35) instead of drawing it from the
+// entropy source.
+func WithSalt(salt int) Option {
+	return func(g *Generator) error {
+		if salt < 0 || salt > 35 {
+			return fmt.Errorf("salt out of range 0..35: %d", salt)
+		}
+		g.node.Salt = salt
+		g.saltSet = true
+		return nil
+	}
+}
+
+// WithStateFile persists the counter state in the file at path, so IDs stay
+// strictly monotonic across processes sharing it.
+func WithStateFile(path string) Option {
+	return func(g *Generator) error {
+		if path == "" {
+			return fmt.Errorf("empty state file path")
+		}
+		g.stateFile = NewStateFile(path)
+		return nil
+	}
+}
+
+// WithShards splits the counter into n lock-independent shards (1..MaxShards)
+// so concurrent callers do not contend on one mutex. Shard k issues the
+// counter values k, k+n, k+2n, ... of each millisecond, so shards never
+// collide and IDs still sort by time; each shard has about 1296/n values per
+// millisecond before it overflows. Not combinable with WithStateFile.
+func WithShards(n int) Option {
+	return func(g *Generator) error {
+		if n < 1 || n > MaxShards {
+			return fmt.Errorf("shards out of range 1..%d: %d", MaxShards, n)
+		}
+		g.shards = make([]*state, n) // sized here, built in New
+		return nil
+	}
+}
+
+// WithAlphabet sets the alphabet IDs render with (default codec.Default).
+func WithAlphabet(a *codec.Alphabet) Option {
+	return func(g *Generator) error {
+		if a != nil {
+			g.alpha = a
+		}
+		return nil
+	}
+}
+
+// WithMixer sets the function that produces TT (default V1Mixer). Classic
+// IDs from a mixer other than v1 keep the node's prefix and salt and add a
+// digit recording the mixer, PTTCCRM.
+func WithMixer(m Mixer) Option {
+	return func(g *Generator) error {
+		if m == nil {
+			return fmt.Errorf("nil mixer")
+		}
+		g.mixer = m
+		return nil
+	}
+}
+
+// WithLayout sets the UUID layout (default codec.ClassicLayout): whether
+// IDs carry a time-mix, and how many counter and random digits. Any layout
+// but the classic one produces extended ^D... UUIDs, which carry no node;
+// random digits are drawn from the entropy source for every ID.
+func WithLayout(l codec.Layout) Option {
+	return func(g *Generator) error {
+		if err := l.Validate(); err != nil {
+			return err
+		}
+		g.layout = l
+		return nil
+	}
+}
+
+// New builds a Generator from opts (timezone, components, warnings) and
+// options for its dependencies.
+func New(opts model.Options, options ...Option) (*Generator, error) {
+	loc, err := timeutil.LoadLocation(opts.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	g := &Generator{
+		opts:    opts,
+		loc:     loc,
+		alpha:   codec.Default,
+		clock:   SystemClock{},
+		entropy: rand.Reader,
+		mixer:   V1Mixer{},
+		layout:  codec.ClassicLayout,
+	}
+	shards := 1
+	for _, o := range options {
+		if err := o(g); err != nil {
+			return nil, err
+		}
+	}
+	if g.shards != nil {
+		shards = len(g.shards)
+	}
+	if shards > 1 && g.stateFile != nil {
+		return nil, fmt.Errorf("a state file cannot be shared by a sharded generator")
+	}
+	if !g.layout.TimeMix && g.mixer.ID() != codec.MixerV1 {
+		return nil, fmt.Errorf("mixer %s needs a layout with time-mix", g.mixer.ID())
+	}
+	g.extended = g.layout != codec.ClassicLayout
+	g.span = g.layout.CounterSpace()
+	if shards > g.span {
+		return nil, fmt.Errorf("%d shards exceed the %d counter values of layout %s", shards, g.span, g.layout)
+	}
+	g.shards = newShards(shards)
+	g.hist = newHistory()
+	switch opts.ClockPolicy {
+	case "", model.ClockError, model.ClockWait, model.ClockLogical, model.ClockWarn:
+	default:
+		return nil, fmt.Errorf("unknown clock policy %q (want error, wait, logical or warn)", opts.ClockPolicy)
+	}
+	switch opts.Overflow {
+	case "", model.OverflowWait, model.OverflowBorrow:
+	default:
+		return nil, fmt.Errorf("unknown overflow policy %q (want wait or borrow)", opts.Overflow)
+	}
+	if opts.MaxDrift < 0 {
+		return nil, fmt.Errorf("negative max drift: %s", opts.MaxDrift)
+	}
+	if !g.prefixSet || !g.saltSet {
+		n, err := randomNode(g.entropy)
+		if err != nil {
+			return nil, fmt.Errorf("node: %w", err)
+		}
+		if !g.prefixSet {
+			g.node.Prefix = n.Prefix
+		}
+		if !g.saltSet {
+			g.node.Salt = n.Salt
+		}
+	}
+	return g, nil
+}
+
+// randomDigit draws a uniform value in 0..n-1 (n <= 256) from r.
+func randomDigit(r io.Reader, n int) (int, error) {
+	limit := 256 - 256%n // reject the biased tail
+	b := make([]byte, 1)
+	for {
+		if _, err := io.ReadFull(r, b); err != nil {
+			return 0, err
+		}
+		if int(b[0]) < limit {
+			return int(b[0]) % n, nil
+		}
+	}
+}
+
+// Next mints the next ID. The string holds warnings, "; "-separated: a clock
+// regression under the warn policy, and a sort-order warning when warnings
+// are enabled and the components could break chronological sorting.
+//
+// Next may wait: for the next millisecond when a millisecond's counter runs
+// out, or for a regressed clock under the wait policy. It returns ctx.Err()
+// if ctx is done first.
+func (g *Generator) Next(ctx context.Context) (codec.ID, string, error) {
+	b, warnMsg, err := g.Reserve(ctx, 1)
+	if err != nil {
+		return codec.ID{}, "", err
+	}
+	id, err := b.ID(0)
+	if err != nil {
+		return codec.ID{}, "", err
+	}
+	return id, warnMsg, nil
+}
+
+// Reserve claims n consecutive counter values under a single lock and
+// returns them as a Block. The block starts where Next would and runs on
+// through the counter, spilling into the following milliseconds when a
+// millisecond's 1296 values run out; those are accounted as borrowed in
+// Drift. A block that ends more than MaxDrift ahead of the wall clock is
+// returned once the clock has caught up to within MaxDrift. Warnings are
+// as for Next.
+func (g *Generator) Reserve(ctx context.Context, n int) (Block, string, error) {
+	if n < 1 {
+		return Block{}, "", fmt.Errorf("invalid count: %d", n)
+	}
+	st := g.pick()
+	ms, cc, ts, clockWarn, err := g.nextCounter(ctx, st, n)
+	if err != nil {
+		return Block{}, "", err
+	}
+
+	b := Block{g: g, ms: ms, ts: ts, offset: st.offset, stride: st.stride, first: (cc - st.offset) / st.stride, n: n}
+	return b, g.warnings(clockWarn), nil
+}
+
+// warnings joins a clock warning with the sort-order warning, if enabled.
+func (g *Generator) warnings(clockWarn string) string {
+	var warnings []string
+	if clockWarn != "" {
+		warnings = append(warnings, clockWarn)
+	}
+	if g.opts.Warn {
+		if w := warnIfSortBroken(g.opts.Components); w != "" {
+			warnings = append(warnings, w)
+		}
+	}
+	if warnings == nil {
+		return ""
+	}
+	return strings.Join(warnings, "; ")
+}
+
+// NextN mints n strictly increasing IDs from one reserved Block.
+func (g *Generator) NextN(ctx context.Context, n int) ([]codec.ID, string, error) {
+	b, warnMsg, err := g.Reserve(ctx, n)
+	if err != nil {
+		return nil, "", err
+	}
+	ids := make([]codec.ID, n)
+	for i := range ids {
+		if ids[i], err = b.ID(i); err != nil {
+			return nil, "", err
+		}
+	}
+	return ids, warnMsg, nil
+}
+
+// Block is a run of consecutive counter values claimed by Reserve. IDs are
+// rendered on demand, so large blocks can be streamed.
+type Block struct {
+	g  *Generator
+	ms int64   // millisecond of the first ID
+	ts tsEntry // its rendered timestamp
+
+	// The block covers the counter values offset + k*stride of its shard,
+	// for k = first, first+1, ... (n values), wrapping into the next
+	// millisecond after the shard's last value.
+	offset, stride int
+	first, n       int
+}
+
+// Len returns the number of IDs in the block.
+func (b Block) Len() int { return b.n }
+
+// ID returns the i'th ID of the block (0 <= i < Len()).
+func (b Block) ID(i int) (codec.ID, error) {
+	if i < 0 || i >= b.n {
+		return codec.ID{}, fmt.Errorf("block index out of range: %d of %d", i, b.n)
+	}
+	per := b.g.laneSize(b.offset, b.stride)
+	k := b.first + i
+	ms, ts := b.ms+int64(k/per), b.ts
+	if ms != b.ms {
+		ts = b.g.timestamp(ms)
+	}
+	return b.g.build(ts, b.offset+(k%per)*b.stride)
+}
+
+// tsEntry is a millisecond's timestamp fields and time-mix, computed once
+// per millisecond and shard.
+type tsEntry struct {
+	ms  int64
+	ts  codec.Timestamp
+	mix int
+	err error
+}
+
+// timestamp computes the fields for ms in the generator's location.
+func (g *Generator) timestamp(ms int64) tsEntry {
+	now := time.UnixMilli(ms).In(g.loc)
+	ts, err := encodeTimestamp(now, g.opts.Components)
+	e := tsEntry{ms: ms, ts: ts, err: err}
+	if g.layout.TimeMix {
+		e.mix = g.mixer.Mix(now, g.node)
+	}
+	return e
+}
+
+// build renders the ID for a millisecond's timestamp and a counter.
+func (g *Generator) build(e tsEntry, cc int) (codec.ID, error) {
+	if e.err != nil {
+		return codec.ID{}, e.err
+	}
+	if !g.opts.Components.UUID {
+		return codec.NewID(e.ts, nil, g.alpha, g.loc)
+	}
+	uuid := codec.UUID{Prefix: g.node.Prefix, Mixer: g.mixer.ID(), TimeMix: e.mix, Counter: cc, Salt: g.node.Salt}
+	if g.extended {
+		uuid = codec.UUID{Prefix: codec.DescriptorPrefix, Layout: g.layout, TimeMix: e.mix, Counter: cc}
+		if g.layout.TimeMix {
+			uuid.Mixer = g.mixer.ID()
+		}
+		for i := 0; i < g.layout.Random; i++ {
+			d, err := randomDigit(g.entropy, 36)
+			if err != nil {
+				return codec.ID{}, fmt.Errorf("random digits: %w", err)
+			}
+			uuid.Random = uuid.Random*36 + d
+		}
+	}
+	return codec.NewID(e.ts, &uuid, g.alpha, g.loc)
+}
+
+// Node returns the generator's prefix and salt.
+func (g *Generator) Node() Node { return g.node }
+
+// The package-level functions share one process-wide counter state and node,
+// so successive calls stay monotonic whatever options they pass.
 var (
-	mu        sync.Mutex
-	lastMs    int64
-	counter   int
-	saltDigit int // 0..35, chosen at init
+	stdState    = newShards(1)[0]
+	stdHistory  = newHistory()
+	stdNodeOnce sync.Once
+	stdNode     Node
+
+	// stdGenerators caches the generator per option set and alphabet
+	// (stdKey -> *Generator), so calls without extra options resolve them
+	// once.
+	stdGenerators sync.Map
 )
 
-func init() {
-	b := make([]byte, 1)
-	_, _ = rand.Read(b)
-	saltDigit = int(b[0] % 36)
+type stdKey struct {
+	opts  model.Options
+	alpha *codec.Alphabet
 }
 
-func Generate(opts model.Options) (vizID string, asciiWire string, warnMsg string, err error) {
-	loc, err := timeutil.LoadLocation(opts.Timezone)
+func processNode() Node {
+	stdNodeOnce.Do(func() {
+		stdNode, _ = randomNode(rand.Reader)
+	})
+	return stdNode
+}
+
+// Generate renders a new ID in the given alphabet. A nil alphabet means codec.Default.
+func Generate(opts model.Options, alpha *codec.Alphabet) (vizID string, asciiWire string, warnMsg string, err error) {
+	id, warnMsg, err := GenerateID(opts, alpha)
 	if err != nil {
 		return "", "", "", err
 	}
-	now := time.Now().In(loc)
-	vizTS, asciiTS, err := encodeTimestamp(now, opts.Components)
+	return id.VIZ(), id.ASCII(), warnMsg, nil
+}
+
+// GenerateID is Generate returning the structured ID. Extra options apply
+// after the alphabet and process node, so they can override either.
+func GenerateID(opts model.Options, alpha *codec.Alphabet, options ...Option) (id codec.ID, warnMsg string, err error) {
+	return GenerateContext(context.Background(), opts, alpha, options...)
+}
+
+// GenerateN reserves n consecutive IDs (see Generator.Reserve) from the
+// process-wide counter.
+func GenerateN(ctx context.Context, opts model.Options, alpha *codec.Alphabet, n int, options ...Option) (Block, string, error) {
+	g, err := stdGenerator(opts, alpha, options)
 	if err != nil {
-		return "", "", "", err
+		return Block{}, "", err
 	}
+	return g.Reserve(ctx, n)
+}
 
-	vizUUID, asciiUUID, err := generateUUID(now, loc)
+// GenerateAt reserves n consecutive IDs for the instant t (see
+// Generator.ReserveAt) from the process-wide per-instant counters.
+func GenerateAt(ctx context.Context, opts model.Options, alpha *codec.Alphabet, t time.Time, n int, options ...Option) (Block, string, error) {
+	g, err := stdGenerator(opts, alpha, options)
 	if err != nil {
-		return "", "", "", err
+		return Block{}, "", err
 	}
-	if !opts.Components.UUID {
-		vizUUID = ""
-		asciiUUID = ""
+	return g.ReserveAt(ctx, t, n)
+}
+
+// GenerateContext is GenerateID giving up with ctx.Err() if ctx is done
+// while waiting for the clock.
+func GenerateContext(ctx context.Context, opts model.Options, alpha *codec.Alphabet, options ...Option) (id codec.ID, warnMsg string, err error) {
+	g, err := stdGenerator(opts, alpha, options)
+	if err != nil {
+		return codec.ID{}, "", err
 	}
+	return g.Next(ctx)
+}
 
-	if opts.Warn {
-		warnMsg = warnIfSortBroken(opts.Components)
+// stdGenerator returns a generator on the process-wide state and node,
+// cached when there are no extra options.
+func stdGenerator(opts model.Options, alpha *codec.Alphabet, options []Option) (*Generator, error) {
+	if len(options) > 0 {
+		return newStdGenerator(opts, alpha, options)
 	}
+	key := stdKey{opts, alpha}
+	if g, ok := stdGenerators.Load(key); ok {
+		return g.(*Generator), nil
+	}
+	g, err := newStdGenerator(opts, alpha, nil)
+	if err != nil {
+		return nil, err
+	}
+	actual, _ := stdGenerators.LoadOrStore(key, g)
+	return actual.(*Generator), nil
+}
 
-	vizID = vizTS
-	asciiWire = asciiTS
-	if opts.Components.UUID {
-		vizID = vizTS + "-" + vizUUID
-		asciiWire = asciiTS + "-" + asciiUUID
+func newStdGenerator(opts model.Options, alpha *codec.Alphabet, options []Option) (*Generator, error) {
+	options = append([]Option{WithAlphabet(alpha), WithNode(processNode())}, options...)
+	g, err := New(opts, options...)
+	if err != nil {
+		return nil, err
+	}
+	if len(g.shards) > 1 {
+		return nil, fmt.Errorf("sharding needs a Generator; the package-level counter is not sharded")
 	}
-	return vizID, asciiWire, warnMsg, nil
+	g.shards[0] = stdState
+	g.hist = stdHistory
+	return g, nil
 }
 
-// encodeTimestamp renders the fixed-width visual timestamp.
+// encodeTimestamp picks the timestamp fields for the enabled components;
+// disabled components are zeroed.
 // v1 field widths: Year=3, Month=1, Day=1, Hour=1, Minute=2, Second=2, Ms=2  (12 total glyphs).
-func encodeTimestamp(t time.Time, c model.Components) (viz string, asciiWire string, err error) {
-	year := int64(t.Year())
-	month := int64(t.Month()) - 1 // 0..11
-	day := int64(t.Day()) - 1     // 0..30
-	hour := int64(t.Hour())       // 0..23
-	min := int64(t.Minute())      // 0..59
-	sec := int64(t.Second())      // 0..59
-	ms := int64(t.Nanosecond() / 1e6)
-
-	asciiYear := fmt.Sprintf("%04d", t.Year())
-	asciiMonth := fmt.Sprintf("%02d", int(t.Month()))
-	asciiDay := fmt.Sprintf("%02d", t.Day())
-	asciiHour := fmt.Sprintf("%02d", t.Hour())
-	asciiMinute := fmt.Sprintf("%02d", t.Minute())
-	asciiSecond := fmt.Sprintf("%02d", t.Second())
-	asciiMs := fmt.Sprintf("%03d", ms)
+func encodeTimestamp(t time.Time, c model.Components) (codec.Timestamp, error) {
+	ts := codec.Timestamp{
+		Year:   t.Year(),
+		Month:  int(t.Month()), // 1..12
+		Day:    t.Day(),        // 1..31
+		Hour:   t.Hour(),       // 0..23
+		Minute: t.Minute(),     // 0..59
+		Second: t.Second(),     // 0..59
+		Millis: t.Nanosecond() / 1e6,
+	}
 
 	if !c.Year {
-		year = 0
-		asciiYear = "0000"
+		ts.Year = 0
 	}
 	if !c.Month {
-		month = 0
-		asciiMonth = "00"
+		ts.Month = 0
 	}
 	if !c.Day {
-		day = 0
-		asciiDay = "00"
+		ts.Day = 0
 	}
 	if !c.Hour {
-		hour = 0
-		asciiHour = "00"
+		ts.Hour = 0
 	}
 	if !c.Minute {
-		min = 0
-		asciiMinute = "00"
+		ts.Minute = 0
 	}
 	if !c.Second {
-		sec = 0
-		asciiSecond = "00"
+		ts.Second = 0
 	}
 	if !c.Ms {
-		ms = 0
-		asciiMs = "000"
+		ts.Millis = 0
 	}
 
 	if c.Year {
-		if year < 0 || year > 9999 {
-			return "", "", fmt.Errorf("year out of v1 range: %d", year)
-		}
-	}
-
-	// ASCII wire timestamp remains decimal digits for human typing/logs.
-	asciiWire = asciiYear + asciiMonth + asciiDay + asciiHour + asciiMinute + asciiSecond + asciiMs
-
-	// Encode in base36 fixed widths
-	y3, err := codec.ToBase36(year, 3); if err != nil { return "", "", err }
-	m1, err := codec.ToBase36(month, 1); if err != nil { return "", "", err }
-	d1, err := codec.ToBase36(day, 1); if err != nil { return "", "", err }
-	h1, err := codec.ToBase36(hour, 1); if err != nil { return "", "", err }
-	mn2, err := codec.ToBase36(min, 2); if err != nil { return "", "", err }
-	s2, err := codec.ToBase36(sec, 2); if err != nil { return "", "", err }
-	ms2, err := codec.ToBase36(ms, 2); if err != nil { return "", "", err }
-
-	b36 := y3 + m1 + d1 + h1 + mn2 + s2 + ms2
-
-	// Map base36 digits to core glyphs
-	runes := make([]rune, 0, len(b36))
-	for _, r := range b36 {
-		val := -1
-		if r >= '0' && r <= '9' { val = int(r-'0') }
-		if r >= 'A' && r <= 'Z' { val = int(r-'A') + 10 }
-		g, e := codec.CoreValToGlyph(val)
-		if e != nil { return "", "", e }
-		runes = append(runes, g)
-	}
-	viz = string(runes)
-	return viz, asciiWire, nil
-}
-
-func generateUUID(now time.Time, loc *time.Location) (viz string, ascii string, err error) {
-	// Prefix: choose from 8 options based on saltDigit (stable per process)
-	prefixes := []byte{'~','!','@','$','%','^','&','*'}
-	p := prefixes[saltDigit%len(prefixes)]
-	pg := codec.PrefixASCII[p]
-
-	// Time-mix uses ms since start of minute
-	in := now.In(loc)
-	t := int64(in.Second()*1000 + in.Nanosecond()/1e6) // 0..59999
-	mixed := mixTime(t)
-	// reduce to 36^2
-	mod := int64(36 * 36)
-	m2 := mixed % mod
-	T, err := codec.ToBase36(m2, 2)
-	if err != nil { return "", "", err }
-
-	// Counter (monotonic within same millisecond)
-	cc, err := nextCounter(in)
-	if err != nil { return "", "", err }
-	C, err := codec.ToBase36(int64(cc), 2)
-	if err != nil { return "", "", err }
-
-	// Salt digit as one base36 char
-	R := string(codecDigit(saltDigit))
-
-	ascii = string([]byte{p}) + T + C + R
-
-	// Render ASCII UUID to VIZ:
-	// P -> prefix glyph, T/C/R digits -> core glyphs
-	vizRunes := []rune{pg}
-	for _, ch := range (T + C + R) {
-		val := -1
-		if ch >= '0' && ch <= '9' { val = int(ch-'0') }
-		if ch >= 'A' && ch <= 'Z' { val = int(ch-'A') + 10 }
-		g, e := codec.CoreValToGlyph(val)
-		if e != nil { return "", "", e }
-		vizRunes = append(vizRunes, g)
-	}
-	viz = string(vizRunes)
-	return viz, ascii, nil
-}
-
-func mixTime(t int64) int64 {
-	// Simple deterministic mix (not cryptographic)
-	// XOR constant + rotate-ish via shifts
-	x := t ^ 0x5A5A
-	x = x + (x >> 3) + (x << 2)
-	return x & 0x7FFFFFFF
-}
-
-func nextCounter(now time.Time) (int, error) {
-	mu.Lock()
-	defer mu.Unlock()
-	ms := now.UnixMilli()
-	if ms > lastMs {
-		lastMs = ms
-		counter = 0
-		return 0, nil
-	}
-	if ms < lastMs {
-		// clock moved backward; in v1, treat as error
-		return 0, fmt.Errorf("clock moved backwards: %d < %d", ms, lastMs)
-	}
-	counter++
-	if counter <= 1295 {
-		return counter, nil
-	}
-	// block/spin until next millisecond tick
-	for {
-		mu.Unlock()
-		time.Sleep(time.Microsecond * 200)
-		mu.Lock()
-		ms2 := time.Now().UnixMilli()
-		if ms2 > lastMs {
-			lastMs = ms2
-			counter = 0
-			return 0, nil
+		if ts.Year < 0 || ts.Year > 9999 {
+			return codec.Timestamp{}, fmt.Errorf("year out of v1 range: %d", ts.Year)
 		}
 	}
-}
-
-func codecDigit(val int) byte {
-	if val < 0 || val >= 36 { return '0' }
-	if val < 10 { return byte('0' + val) }
-	return byte('A' + (val - 10))
+	return ts, nil
 }
 
 func warnIfSortBroken(c model.Components) string {
 	// Very conservative: if you disable a more-significant field but keep any less-significant fields,
 	// warn that sorting could break.
-	sig := []struct{
-		name string
+	sig := []struct {
+		name    string
 		enabled bool
 	}{
 		{"year", c.Year},
@@ -0,0 +1,466 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ryanl/vizid/internal/codec"
+	"github.com/ryanl/vizid/internal/model"
+)
+
+var t0 = time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC)
+
+func testOptions() model.Options {
+	return model.Options{Components: model.Components{
+		Year: true, Month: true, Day: true, Hour: true, Minute: true, Second: true, Ms: true, UUID: true,
+	}}
+}
+
+// newTestGenerator builds a generator on clock with a fixed node, so its IDs
+// are deterministic.
+func newTestGenerator(t testing.TB, opts model.Options, clock Clock, options ...Option) *Generator {
+	t.Helper()
+	options = append([]Option{WithClock(clock), WithNode(Node{Prefix: '%', Salt: 7})}, options...)
+	g, err := New(opts, options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+// next is g.Next with a background context, failing the test on error.
+func next(t testing.TB, g *Generator) codec.ID {
+	t.Helper()
+	id, _, err := g.Next(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+// msOf returns the millisecond an ID is stamped with, relative to t0.
+func msOf(id codec.ID) int64 {
+	return id.Time().Sub(t0).Milliseconds()
+}
+
+func TestGeneratorsDoNotShareState(t *testing.T) {
+	clock := NewFakeClock(t0)
+	g1 := newTestGenerator(t, testOptions(), clock)
+	g2 := newTestGenerator(t, testOptions(), clock)
+
+	for i := 0; i < 1296; i++ {
+		next(t, g1)
+	}
+	id := next(t, g2)
+	if id.Counter() != 0 || msOf(id) != 0 {
+		t.Errorf("g2 after g1 used a whole millisecond: got counter %d at +%dms, want 0 at +0ms", id.Counter(), msOf(id))
+	}
+	if d := g2.Drift(); d.Borrows != 0 {
+		t.Errorf("g2 borrowed %d ms for g1", d.Borrows)
+	}
+}
+
+func TestDeterministicClockAndEntropy(t *testing.T) {
+	mint := func() []string {
+		clock := NewFakeClock(t0)
+		clock.SetStep(300 * time.Microsecond)
+		entropy := bytes.NewReader(bytes.Repeat([]byte{250, 40, 17}, 64))
+		g, err := New(testOptions(), WithClock(clock), WithEntropy(entropy))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for i := 0; i < 10; i++ {
+			ids = append(ids, next(t, g).ASCII())
+		}
+		return ids
+	}
+	a, b := mint(), mint()
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("ID %d: %s then %s from the same clock and entropy", i, a[i], b[i])
+		}
+		if i > 0 && a[i] <= a[i-1] {
+			t.Errorf("ID %d: %s does not sort after %s", i, a[i], a[i-1])
+		}
+	}
+}
+
+func TestClockPolicies(t *testing.T) {
+	tests := []struct {
+		policy   model.ClockPolicy
+		wantErr  bool
+		wantMs   int64 // of the ID minted after the clock steps back 5ms
+		wantWarn bool
+		wantLate bool // the ID sorts after the ones before the step
+	}{
+		{policy: model.ClockError, wantErr: true},
+		{policy: model.ClockWait, wantMs: 0, wantLate: true},
+		{policy: model.ClockLogical, wantMs: 0, wantLate: true},
+		{policy: model.ClockWarn, wantMs: -5, wantWarn: true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			opts := testOptions()
+			opts.ClockPolicy = tt.policy
+			clock := NewFakeClock(t0)
+			g := newTestGenerator(t, opts, clock)
+			before := []codec.ID{next(t, g), next(t, g)}
+
+			clock.Set(t0.Add(-5 * time.Millisecond))
+			id, warn, err := g.Next(context.Background())
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %s, want an error", id.ASCII())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := msOf(id); got != tt.wantMs {
+				t.Errorf("stamped +%dms, want +%dms", got, tt.wantMs)
+			}
+			if (warn != "") != tt.wantWarn {
+				t.Errorf("warning %q, want one: %v", warn, tt.wantWarn)
+			}
+			last := before[len(before)-1].ASCII()
+			if late := id.ASCII() > last; late != tt.wantLate {
+				t.Errorf("%s after %s: %v, want %v", id.ASCII(), last, late, tt.wantLate)
+			}
+			for _, b := range before {
+				if b.ASCII() == id.ASCII() {
+					t.Errorf("%s issued twice", id.ASCII())
+				}
+			}
+		})
+	}
+}
+
+func TestClockWarnNeverRepeats(t *testing.T) {
+	opts := testOptions()
+	opts.ClockPolicy = model.ClockWarn
+	clock := NewFakeClock(t0)
+	g := newTestGenerator(t, opts, clock)
+
+	// the clock steps back and forth over the same two milliseconds
+	steps := []time.Duration{0, 0, time.Millisecond, 0, 0, time.Millisecond, time.Millisecond, 0}
+	seen := map[string]bool{}
+	warnings := 0
+	for i, d := range steps {
+		clock.Set(t0.Add(d))
+		id, warn, err := g.Next(context.Background())
+		if err != nil {
+			t.Fatalf("step %d: %v", i, err)
+		}
+		if seen[id.ASCII()] {
+			t.Fatalf("step %d: %s issued twice", i, id.ASCII())
+		}
+		seen[id.ASCII()] = true
+		if warn != "" {
+			warnings++
+		}
+	}
+	if warnings != 2 {
+		t.Errorf("got %d warnings, want one per step back (2)", warnings)
+	}
+}
+
+func TestOverflow(t *testing.T) {
+	tests := []struct {
+		name        string
+		overflow    model.OverflowPolicy
+		maxDrift    time.Duration
+		n           int
+		wantLastMs  int64
+		wantBorrows uint64
+		wantMax     time.Duration
+		wantClock   time.Duration // how far the fake clock moved
+	}{
+		{name: "wait", overflow: model.OverflowWait, n: 1297, wantLastMs: 1, wantClock: time.Millisecond},
+		{name: "borrow", overflow: model.OverflowBorrow, n: 3*1296 + 1, wantLastMs: 3, wantBorrows: 3, wantMax: 3 * time.Millisecond},
+		{name: "borrow within max drift", overflow: model.OverflowBorrow, maxDrift: 2 * time.Millisecond, n: 3*1296 + 1, wantLastMs: 3, wantBorrows: 3, wantMax: 2 * time.Millisecond, wantClock: time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := testOptions()
+			opts.Overflow, opts.MaxDrift = tt.overflow, tt.maxDrift
+			clock := NewFakeClock(t0)
+			g := newTestGenerator(t, opts, clock)
+
+			var prev, id codec.ID
+			for i := 0; i < tt.n; i++ {
+				id = next(t, g)
+				if i > 0 && id.ASCII() <= prev.ASCII() {
+					t.Fatalf("ID %d: %s does not sort after %s", i, id.ASCII(), prev.ASCII())
+				}
+				prev = id
+			}
+			if got := msOf(id); got != tt.wantLastMs {
+				t.Errorf("last ID at +%dms, want +%dms", got, tt.wantLastMs)
+			}
+			d := g.Drift()
+			if d.Borrows != tt.wantBorrows || d.Max != tt.wantMax {
+				t.Errorf("Drift() = %+v, want %d borrows and max %v", d, tt.wantBorrows, tt.wantMax)
+			}
+			if got := clock.Now().Sub(t0); got < tt.wantClock || got >= tt.wantClock+time.Millisecond {
+				t.Errorf("clock moved %v, want %v", got, tt.wantClock)
+			}
+		})
+	}
+}
+
+// cancelClock is a FakeClock that cancels a context when it is first asked
+// to sleep, as if the caller gave up during the wait.
+type cancelClock struct {
+	*FakeClock
+	cancel context.CancelFunc
+}
+
+func (c *cancelClock) Sleep(d time.Duration) {
+	c.cancel()
+	c.FakeClock.Sleep(d)
+}
+
+func TestContextCancellation(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  func(*model.Options)
+		setup func(g *Generator, clock *FakeClock) // brings g to the point of waiting
+		early bool                                 // cancel before the call
+	}{
+		{
+			name:  "canceled before the call",
+			setup: func(*Generator, *FakeClock) {},
+			early: true,
+		},
+		{
+			name: "waiting for the next millisecond",
+			setup: func(g *Generator, _ *FakeClock) {
+				for i := 0; i < 1296; i++ {
+					_, _, _ = g.Next(context.Background())
+				}
+			},
+		},
+		{
+			name: "waiting for a regressed clock",
+			opts: func(o *model.Options) { o.ClockPolicy = model.ClockWait },
+			setup: func(g *Generator, clock *FakeClock) {
+				_, _, _ = g.Next(context.Background())
+				clock.Set(t0.Add(-time.Second))
+			},
+		},
+		{
+			name: "waiting within max drift",
+			opts: func(o *model.Options) { o.Overflow, o.MaxDrift = model.OverflowBorrow, time.Millisecond },
+			setup: func(g *Generator, _ *FakeClock) {
+				for i := 0; i < 2*1296; i++ {
+					_, _, _ = g.Next(context.Background())
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := testOptions()
+			if tt.opts != nil {
+				tt.opts(&opts)
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			clock := &cancelClock{FakeClock: NewFakeClock(t0), cancel: func() {}}
+			g := newTestGenerator(t, opts, clock)
+			tt.setup(g, clock.FakeClock)
+			if tt.early {
+				cancel()
+			}
+			clock.cancel = cancel
+
+			id, _, err := g.Next(ctx)
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("got %s, %v; want context.Canceled", id.ASCII(), err)
+			}
+		})
+	}
+}
+
+func TestReserve(t *testing.T) {
+	tests := []struct {
+		name        string
+		shards      int
+		n           int
+		wantLastMs  int64
+		wantBorrows uint64
+	}{
+		{name: "one", shards: 1, n: 1},
+		{name: "whole millisecond", shards: 1, n: 1296},
+		{name: "spills into the next millisecond", shards: 1, n: 1297, wantLastMs: 1, wantBorrows: 1},
+		{name: "spills over two milliseconds", shards: 1, n: 3000, wantLastMs: 2, wantBorrows: 2},
+		{name: "sharded lane", shards: 4, n: 324},
+		{name: "sharded lane spills", shards: 4, n: 325, wantLastMs: 1, wantBorrows: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestGenerator(t, testOptions(), NewFakeClock(t0), WithShards(tt.shards))
+			b, _, err := g.Reserve(context.Background(), tt.n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if b.Len() != tt.n {
+				t.Fatalf("Len() = %d, want %d", b.Len(), tt.n)
+			}
+			var prev codec.ID
+			for i := 0; i < b.Len(); i++ {
+				id, err := b.ID(i)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if i > 0 && id.ASCII() <= prev.ASCII() {
+					t.Fatalf("ID %d: %s does not sort after %s", i, id.ASCII(), prev.ASCII())
+				}
+				prev = id
+			}
+			if got := msOf(prev); got != tt.wantLastMs {
+				t.Errorf("last ID at +%dms, want +%dms", got, tt.wantLastMs)
+			}
+			if d := g.Drift(); d.Borrows != tt.wantBorrows {
+				t.Errorf("borrowed %d ms, want %d", d.Borrows, tt.wantBorrows)
+			}
+			if tt.shards == 1 {
+				if id := next(t, g); id.ASCII() <= prev.ASCII() {
+					t.Errorf("Next after the block: %s does not sort after %s", id.ASCII(), prev.ASCII())
+				}
+			}
+		})
+	}
+}
+
+func TestReserveWithinMaxDrift(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxDrift  time.Duration
+		n         []int // successive Reserve calls
+		wantClock time.Duration
+	}{
+		{name: "one block", maxDrift: 5 * time.Millisecond, n: []int{1296 * 50}, wantClock: 44 * time.Millisecond},
+		{name: "block after a block", maxDrift: 2 * time.Millisecond, n: []int{2*1296 + 1, 1296}, wantClock: time.Millisecond},
+		{name: "within the limit", maxDrift: 2 * time.Millisecond, n: []int{3 * 1296}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := testOptions()
+			opts.MaxDrift = tt.maxDrift
+			clock := NewFakeClock(t0)
+			g := newTestGenerator(t, opts, clock)
+			var last codec.ID
+			for _, n := range tt.n {
+				b, _, err := g.Reserve(context.Background(), n)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if last, err = b.ID(n - 1); err != nil {
+					t.Fatal(err)
+				}
+				if d := last.Time().Sub(clock.Now()); d > opts.MaxDrift {
+					t.Errorf("block ends %v ahead of the clock, more than %v", d, opts.MaxDrift)
+				}
+			}
+			if d := g.Drift(); d.Max > opts.MaxDrift {
+				t.Errorf("Drift().Max = %v, more than %v", d.Max, opts.MaxDrift)
+			}
+			if got := clock.Now().Sub(t0); got != tt.wantClock {
+				t.Errorf("clock moved %v, want %v", got, tt.wantClock)
+			}
+		})
+	}
+}
+
+func TestReserveInvalidCount(t *testing.T) {
+	g := newTestGenerator(t, testOptions(), NewFakeClock(t0))
+	for _, n := range []int{0, -1} {
+		if _, _, err := g.Reserve(context.Background(), n); err == nil {
+			t.Errorf("Reserve(%d): want an error", n)
+		}
+	}
+}
+
+func TestShardsUnique(t *testing.T) {
+	const callers, perCaller = 8, 500
+	for _, shards := range []int{1, 2, 7, MaxShards} {
+		t.Run(fmt.Sprintf("shards=%d", shards), func(t *testing.T) {
+			opts := testOptions()
+			opts.Overflow = model.OverflowBorrow
+			g := newTestGenerator(t, opts, NewFakeClock(t0), WithShards(shards))
+
+			var mu sync.Mutex
+			seen := map[string]bool{}
+			var wg sync.WaitGroup
+			for c := 0; c < callers; c++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := 0; i < perCaller; i++ {
+						id, _, err := g.Next(context.Background())
+						if err != nil {
+							t.Error(err)
+							return
+						}
+						mu.Lock()
+						if seen[id.ASCII()] {
+							t.Errorf("%s issued twice", id.ASCII())
+						}
+						seen[id.ASCII()] = true
+						mu.Unlock()
+					}
+				}()
+			}
+			wg.Wait()
+			if len(seen) != callers*perCaller {
+				t.Errorf("got %d distinct IDs, want %d", len(seen), callers*perCaller)
+			}
+		})
+	}
+}
+
+func TestKeyedMixerKeepsNode(t *testing.T) {
+	m, err := NewKeyedMixer([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := newTestGenerator(t, testOptions(), NewFakeClock(t0), WithMixer(m))
+	id := next(t, g)
+	if id.Prefix() != '%' || id.Salt() != 7 || id.Mixer() != codec.MixerKeyed || id.IsExtended() {
+		t.Fatalf("%s: got prefix %q, salt %d, mixer %s, extended %v; want %%, 7, keyed, classic",
+			id.ASCII(), id.Prefix(), id.Salt(), id.Mixer(), id.IsExtended())
+	}
+	back, err := codec.Parse(id.ASCII())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if back.UUID() != id.UUID() {
+		t.Errorf("%s parses as %+v, want %+v", id.ASCII(), back.UUID(), id.UUID())
+	}
+}
+
+func TestPackageLevelGeneratorCache(t *testing.T) {
+	opts := testOptions()
+	g1, err := stdGenerator(opts, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g2, _ := stdGenerator(opts, nil, nil); g2 != g1 {
+		t.Error("the same options built a second generator")
+	}
+	opts.Timezone = "Europe/Berlin"
+	if g3, _ := stdGenerator(opts, nil, nil); g3 == g1 {
+		t.Error("different options reused the generator")
+	}
+	if g4, _ := stdGenerator(testOptions(), nil, []Option{WithSalt(3)}); g4 == g1 {
+		t.Error("extra options reused the cached generator")
+	}
+}
@@ -0,0 +1,268 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ryanl/vizid/internal/codec"
+)
+
+// maxInstants bounds the per-instant counters a history keeps; beyond it the
+// oldest are folded into one bound for every millisecond up to them.
+const maxInstants = 1024
+
+// noLive is history.liveFrom before the live counter has stamped anything.
+const noLive = math.MaxInt64
+
+// history keeps a monotonic counter per explicit instant, so many IDs
+// minted for the same historical millisecond get distinct, ordered
+// counters. It also remembers the first millisecond the live counter
+// stamped: explicit instants must stay before it to never reuse a value the
+// live counter issued.
+type history struct {
+	mu   sync.Mutex
+	next map[int64]int // millisecond -> next free counter (the counter space = full)
+
+	// Folded instants: every millisecond up to uptoMs has the counters
+	// below uptoNext taken (none when uptoNext is 0).
+	uptoMs   int64
+	uptoNext int
+
+	liveFrom atomic.Int64 // first live millisecond, noLive until one is issued
+}
+
+func newHistory() *history {
+	h := &history{next: map[int64]int{}}
+	h.liveFrom.Store(noLive)
+	return h
+}
+
+func (h *history) markLive(ms int64) {
+	for {
+		cur := h.liveFrom.Load()
+		if ms >= cur || h.liveFrom.CompareAndSwap(cur, ms) {
+			return
+		}
+	}
+}
+
+// used returns the next free counter of ms. Called with h.mu held.
+func (h *history) used(ms int64) int {
+	next := h.next[ms]
+	if ms <= h.uptoMs && h.uptoNext > next {
+		next = h.uptoNext
+	}
+	return next
+}
+
+// NextAt mints an ID for the instant t instead of the clock's current time.
+// See ReserveAt.
+func (g *Generator) NextAt(ctx context.Context, t time.Time) (codec.ID, string, error) {
+	b, warnMsg, err := g.ReserveAt(ctx, t, 1)
+	if err != nil {
+		return codec.ID{}, "", err
+	}
+	id, err := b.ID(0)
+	if err != nil {
+		return codec.ID{}, "", err
+	}
+	return id, warnMsg, nil
+}
+
+// ReserveAt claims n consecutive counter values for the instant t, for
+// backfilling historical records. Each millisecond has its own counter, so
+// repeated calls for one instant continue where the last left off; a full
+// millisecond spills into the next, as Reserve does.
+//
+// Without a state file, t must be in the past, and before the first ID
+// this generator issued from the clock: the live counter owns those
+// milliseconds. The counters then live in memory only, so separate
+// processes backfilling the same instant need distinct nodes.
+//
+// With a state file the counters are kept in it, under its lock, so every
+// process sharing the file continues them. The IDs must then be in the past
+// and clear of the latest live millisecond in the file; an instant the live
+// counter has passed gets counters above every live one issued.
+func (g *Generator) ReserveAt(ctx context.Context, t time.Time, n int) (Block, string, error) {
+	if n < 1 {
+		return Block{}, "", fmt.Errorf("invalid count: %d", n)
+	}
+	if err := ctx.Err(); err != nil {
+		return Block{}, "", err
+	}
+	if g.stateFile != nil {
+		return g.reserveAtShared(t, n)
+	}
+	h := g.hist
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ms, first, lastMs, err := h.claim(t, n, g.span, h.used)
+	if err != nil {
+		return Block{}, "", err
+	}
+	if now := g.clock.Now().UnixMilli(); lastMs >= now {
+		return Block{}, "", fmt.Errorf("instant %s is not in the past", formatMs(lastMs))
+	}
+	if live := h.liveFrom.Load(); lastMs >= live {
+		return Block{}, "", fmt.Errorf("instant %s is not before this generator's first live ID at %s", formatMs(lastMs), formatMs(live))
+	}
+	h.commit(ms, first, n, g.span)
+	return Block{g: g, ms: ms, ts: g.timestamp(ms), offset: 0, stride: 1, first: first, n: n}, g.warnings(""), nil
+}
+
+// reserveAtShared is ReserveAt for a generator with a state file. The file
+// stays locked from reading the stored counters until the new ones are
+// written back. State files rule out shards, so there is one.
+func (g *Generator) reserveAtShared(t time.Time, n int) (Block, string, error) {
+	fh, err := g.stateFile.acquire()
+	if err != nil {
+		return Block{}, "", err
+	}
+	defer g.stateFile.release(fh)
+	stored, err := g.stateFile.read(fh)
+	if err != nil {
+		return Block{}, "", err
+	}
+	st := g.shards[0]
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.merge(stored)
+	h := g.hist
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.merge(stored)
+
+	// Live IDs may hold any counter up to peak in a millisecond from the
+	// first live one to the latest.
+	live := h.liveFrom.Load()
+	if at := t.UnixMilli(); live <= at && at < st.lastMs && st.peak+1 >= g.span {
+		return Block{}, "", fmt.Errorf("cannot reserve IDs at %s: live IDs from %s to %s in %s may use every counter", t.Format(time.RFC3339Nano), formatMs(live), formatMs(st.lastMs), g.stateFile.Path())
+	}
+	ms, first, lastMs, err := h.claim(t, n, g.span, func(x int64) int {
+		if live <= x && x < st.lastMs && st.peak >= h.used(x) {
+			return st.peak + 1
+		}
+		return h.used(x)
+	})
+	if err != nil {
+		return Block{}, "", err
+	}
+	now := g.clock.Now().UnixMilli()
+	if lastMs >= now {
+		return Block{}, "", fmt.Errorf("instant %s is not in the past", formatMs(lastMs))
+	}
+	if ms <= st.lastMs && st.lastMs <= lastMs {
+		return Block{}, "", fmt.Errorf("cannot reserve %d IDs at %s: the block would run into %s, the latest live millisecond in %s", n, t.Format(time.RFC3339Nano), formatMs(st.lastMs), g.stateFile.Path())
+	}
+	h.commit(ms, first, n, g.span)
+	// the live counter must not come back to the reserved milliseconds
+	if now > st.wallMs {
+		st.wallMs = now
+	}
+	if err := g.stateFile.write(fh, st.stored(h)); err != nil {
+		return Block{}, "", err
+	}
+	return Block{g: g, ms: ms, ts: g.timestamp(ms), offset: 0, stride: 1, first: first, n: n}, g.warnings(""), nil
+}
+
+// liveStart returns the first counter of the lane offset, stride in ms that
+// ReserveAt has not given out, for the live counter entering ms.
+func (h *history) liveStart(ms int64, offset, stride int) int {
+	h.mu.Lock()
+	next := h.used(ms)
+	h.mu.Unlock()
+	if next <= offset {
+		return offset
+	}
+	return offset + (next-offset+stride-1)/stride*stride
+}
+
+// claim finds room for n counter values from the instant t on, skipping
+// milliseconds with none free; free returns the first free counter of a
+// millisecond. It returns the first millisecond and counter, and the
+// millisecond the block ends in. Called with h.mu held.
+func (h *history) claim(t time.Time, n, span int, free func(int64) int) (int64, int, int64, error) {
+	ms := t.UnixMilli()
+	if ms <= h.uptoMs && h.uptoNext >= span {
+		ms = h.uptoMs + 1
+	}
+	for free(ms) >= span {
+		ms++
+	}
+	first := free(ms)
+	lastMs := ms + int64((first+n-1)/span)
+	for x := ms + 1; x <= lastMs; x++ {
+		if free(x) != 0 {
+			return 0, 0, 0, fmt.Errorf("cannot reserve %d IDs at %s: the block would run into %s, which already has IDs", n, t.Format(time.RFC3339Nano), formatMs(x))
+		}
+	}
+	return ms, first, lastMs, nil
+}
+
+// commit records n values claimed from counter first of millisecond ms.
+// Called with h.mu held.
+func (h *history) commit(ms int64, first, n, span int) {
+	end := first + n - 1
+	lastMs := ms + int64(end/span)
+	for x := ms; x < lastMs; x++ {
+		h.next[x] = span
+	}
+	h.next[lastMs] = end%span + 1
+	h.prune()
+}
+
+// prune folds the oldest instants into the upto bound once there are more
+// than maxInstants, keeping the newest half. The bound only overstates what
+// is taken, so folding never lets a counter be given out twice. Called with
+// h.mu held.
+func (h *history) prune() {
+	if len(h.next) <= maxInstants {
+		return
+	}
+	keys := make([]int64, 0, len(h.next))
+	for ms := range h.next {
+		keys = append(keys, ms)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	for _, ms := range keys[:len(keys)-maxInstants/2] {
+		if ms > h.uptoMs {
+			h.uptoMs = ms
+		}
+		if next := h.next[ms]; next > h.uptoNext {
+			h.uptoNext = next
+		}
+		delete(h.next, ms)
+	}
+}
+
+// merge takes in the counters another process stored, keeping the higher
+// of each, and its first live millisecond if earlier. Called with h.mu held.
+func (h *history) merge(stored storedState) {
+	for ms, next := range stored.at {
+		if next > h.next[ms] {
+			h.next[ms] = next
+		}
+	}
+	if stored.uptoNext > 0 {
+		if stored.uptoMs > h.uptoMs {
+			h.uptoMs = stored.uptoMs
+		}
+		if stored.uptoNext > h.uptoNext {
+			h.uptoNext = stored.uptoNext
+		}
+	}
+	if stored.lastMs != 0 {
+		h.markLive(stored.liveFrom)
+	}
+	h.prune()
+}
+
+func formatMs(ms int64) string {
+	return time.UnixMilli(ms).UTC().Format(time.RFC3339Nano)
+}
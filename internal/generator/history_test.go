@@ -0,0 +1,205 @@
+package generator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReserveAtSharedStateFile(t *testing.T) {
+	at := time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(at.Add(time.Hour))
+	path := filepath.Join(t.TempDir(), "state")
+	node := WithNode(Node{Prefix: '%', Salt: 7})
+
+	seen := map[string]bool{}
+	for run := 0; run < 2; run++ {
+		g, err := New(testOptions(), WithClock(clock), WithStateFile(path), node)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 3; i++ {
+			id, _, err := g.NextAt(context.Background(), at)
+			if err != nil {
+				t.Fatalf("run %d: NextAt: %v", run, err)
+			}
+			if seen[id.ASCII()] {
+				t.Fatalf("run %d: %s issued twice", run, id.ASCII())
+			}
+			seen[id.ASCII()] = true
+		}
+	}
+}
+
+func TestReserveAt(t *testing.T) {
+	at := time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		now     time.Time
+		live    bool // issue a live ID first
+		n       int
+		wantErr bool
+	}{
+		{name: "past", now: at.Add(time.Hour), n: 3},
+		{name: "spills into next ms", now: at.Add(time.Hour), n: 2000},
+		{name: "future", now: at.Add(-time.Hour), n: 1, wantErr: true},
+		{name: "current millisecond", now: at, n: 1, wantErr: true},
+		{name: "after first live ID", now: at.Add(-time.Millisecond), live: true, n: 1, wantErr: true},
+		{name: "before first live ID", now: at.Add(time.Millisecond), live: true, n: 1},
+		{name: "zero count", now: at.Add(time.Hour), n: 0, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := NewFakeClock(tt.now)
+			g, err := New(testOptions(), WithClock(clock))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.live {
+				if _, _, err := g.Next(context.Background()); err != nil {
+					t.Fatal(err)
+				}
+				clock.Set(at.Add(time.Hour))
+			}
+			b, _, err := g.ReserveAt(context.Background(), at, tt.n)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReserveAt: err = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			prev := ""
+			for i := 0; i < b.Len(); i++ {
+				id, err := b.ID(i)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if s := id.ASCII(); s <= prev {
+					t.Fatalf("ID %d %s does not sort after %s", i, s, prev)
+				} else {
+					prev = s
+				}
+			}
+		})
+	}
+}
+
+func TestLiveCounterSkipsReservedCounters(t *testing.T) {
+	at := time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(at.Add(time.Millisecond))
+	g, err := New(testOptions(), WithClock(clock), WithNode(Node{Prefix: '%', Salt: 7}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := map[string]bool{}
+	for i := 0; i < 3; i++ {
+		id, _, err := g.NextAt(context.Background(), at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen[id.ASCII()] = true
+	}
+	// the clock steps back into the reserved millisecond before the first
+	// live ID
+	clock.Set(at)
+	id, _, err := g.Next(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seen[id.ASCII()] {
+		t.Fatalf("%s issued by both NextAt and Next", id.ASCII())
+	}
+	if id.Counter() != 3 {
+		t.Errorf("live counter %d, want 3", id.Counter())
+	}
+}
+
+func TestReserveAtSharedLiveRange(t *testing.T) {
+	now := time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		live      []int // blocks the live counter reserves, 1ms apart from now
+		at        time.Time
+		wantFirst int
+		wantErr   bool
+	}{
+		{name: "before a full live millisecond", live: []int{1296}, at: now.Add(-10 * time.Minute)},
+		{name: "long before the live counter", live: []int{1296}, at: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "before a short live run", live: []int{3}, at: now.Add(-time.Millisecond)},
+		{name: "among short live runs", live: []int{3, 1}, at: now, wantFirst: 3},
+		{name: "among full live milliseconds", live: []int{1296, 1}, at: now, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := NewFakeClock(now)
+			path := filepath.Join(t.TempDir(), "state")
+			for _, n := range tt.live {
+				g, err := New(testOptions(), WithClock(clock), WithStateFile(path))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, _, err := g.Reserve(context.Background(), n); err != nil {
+					t.Fatal(err)
+				}
+				clock.Advance(time.Millisecond)
+			}
+			clock.Advance(time.Minute)
+			g, err := New(testOptions(), WithClock(clock), WithStateFile(path))
+			if err != nil {
+				t.Fatal(err)
+			}
+			id, _, err := g.NextAt(context.Background(), tt.at)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NextAt: err = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !id.Time().Equal(tt.at) || id.Counter() != tt.wantFirst {
+				t.Errorf("got %s, want counter %d at %s", id.ASCII(), tt.wantFirst, tt.at.Format(time.RFC3339Nano))
+			}
+		})
+	}
+}
+
+func TestReserveAtPrunesInstants(t *testing.T) {
+	at := time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(at.Add(time.Hour))
+	path := filepath.Join(t.TempDir(), "state")
+	g, err := New(testOptions(), WithClock(clock), WithStateFile(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _, err := g.NextAt(context.Background(), at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= maxInstants; i++ {
+		if _, _, err := g.NextAt(context.Background(), at.Add(time.Duration(i)*time.Millisecond)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(b), "\n"); lines > maxInstants {
+		t.Errorf("state file has %d lines, want at most %d", lines, maxInstants)
+	}
+
+	// a pruned instant still continues above the counters it gave out
+	g, err = New(testOptions(), WithClock(clock), WithStateFile(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, _, err := g.NextAt(context.Background(), at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.Counter() <= first.Counter() {
+		t.Errorf("%s after pruning does not continue above %s", id.ASCII(), first.ASCII())
+	}
+}
@@ -0,0 +1,91 @@
+package generator
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/ryanl/vizid/internal/codec"
+)
+
+// Mixer produces TT, the time-mix of an ID, for the instant an ID is
+// stamped with (in the generator's location) and the generator's node. It
+// runs once per millisecond per shard, so it need not be cheap, but it must
+// be deterministic and safe for concurrent use.
+type Mixer interface {
+	// ID names the mixer; it is recorded in the IDs it helps produce.
+	ID() codec.MixerID
+	// Mix returns TT, 0..1295.
+	Mix(t time.Time, node Node) int
+}
+
+// V1Mixer is the original time-mix: a fixed XOR/shift mix of the
+// milliseconds within the minute. It ignores the node, so TT says nothing
+// about which host produced an ID.
+type V1Mixer struct{}
+
+// ID returns codec.MixerV1.
+func (V1Mixer) ID() codec.MixerID { return codec.MixerV1 }
+
+// Mix computes TT for an instant.
+func (V1Mixer) Mix(in time.Time, _ Node) int {
+	// Time-mix uses ms since start of minute
+	t := int64(in.Second()*1000 + in.Nanosecond()/1e6) // 0..59999
+	mixed := mixTime(t)
+	// reduce to 36^2
+	mod := int64(36 * 36)
+	return int(mixed % mod)
+}
+
+func mixTime(t int64) int64 {
+	// Simple deterministic mix (not cryptographic)
+	// XOR constant + rotate-ish via shifts
+	x := t ^ 0x5A5A
+	x = x + (x >> 3) + (x << 2)
+	return x & 0x7FFFFFFF
+}
+
+// KeyedMixer derives TT from HMAC-SHA256, keyed with a secret, over the
+// full timestamp (Unix milliseconds) and the node. Different hosts then get
+// unrelated TT values for the same millisecond, and TT cannot be predicted
+// without the secret.
+type KeyedMixer struct {
+	key []byte
+}
+
+// NewKeyedMixer returns a KeyedMixer keyed with secret, which must not be
+// empty.
+func NewKeyedMixer(secret []byte) (*KeyedMixer, error) {
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("keyed mixer needs a secret")
+	}
+	return &KeyedMixer{key: append([]byte(nil), secret...)}, nil
+}
+
+// ID returns codec.MixerKeyed.
+func (*KeyedMixer) ID() codec.MixerID { return codec.MixerKeyed }
+
+// Mix returns the first 8 bytes of HMAC-SHA256(secret, ms || prefix ||
+// salt), big-endian, modulo 1296.
+func (m *KeyedMixer) Mix(t time.Time, node Node) int {
+	var msg [10]byte
+	binary.BigEndian.PutUint64(msg[:8], uint64(t.UnixMilli()))
+	msg[8], msg[9] = node.Prefix, byte(node.Salt)
+	h := hmac.New(sha256.New, m.key)
+	h.Write(msg[:])
+	return int(binary.BigEndian.Uint64(h.Sum(nil)[:8]) % (36 * 36))
+}
+
+// ParseMixer returns the mixer named name: "v1" (or empty), or "keyed",
+// which needs secret.
+func ParseMixer(name string, secret []byte) (Mixer, error) {
+	switch name {
+	case "", "v1":
+		return V1Mixer{}, nil
+	case "keyed":
+		return NewKeyedMixer(secret)
+	}
+	return nil, fmt.Errorf("unknown mixer %q (want v1 or keyed)", name)
+}
@@ -0,0 +1,96 @@
+package generator
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ryanl/vizid/internal/codec"
+)
+
+// Node is a generator's identity: the UUID prefix P and salt digit R. The
+// two are independent, giving len(NodePrefixes) * 36 = 216 distinct nodes.
+type Node struct {
+	Prefix byte // one of NodePrefixes
+	Salt   int  // 0..35
+}
+
+// NodePrefixes are the prefixes a node may use: codec.PrefixSet without
+// codec.DescriptorPrefix and codec.ContentPrefix.
+const NodePrefixes = "!$%&*@"
+
+// String renders the node as its prefix and base-36 salt digit, e.g. "%7".
+func (n Node) String() string {
+	d, _ := codec.ToBase36(int64(n.Salt), 1)
+	return string(n.Prefix) + d
+}
+
+func (n Node) validate() error {
+	switch n.Prefix {
+	case codec.ContentPrefix:
+		return fmt.Errorf("node prefix %q is reserved for content-derived IDs", n.Prefix)
+	case codec.DescriptorPrefix:
+		return fmt.Errorf("node prefix %q is reserved for extended UUIDs", n.Prefix)
+	}
+	if strings.IndexByte(NodePrefixes, n.Prefix) < 0 {
+		return fmt.Errorf("node prefix %q not in %q", n.Prefix, NodePrefixes)
+	}
+	if n.Salt < 0 || n.Salt > 35 {
+		return fmt.Errorf("node salt out of range 0..35: %d", n.Salt)
+	}
+	return nil
+}
+
+// ParseNode reads a node written as String renders it: a prefix character
+// followed by one base-36 digit (either case).
+func ParseNode(s string) (Node, error) {
+	if len(s) != 2 {
+		return Node{}, fmt.Errorf("invalid node id %q: want a prefix from %q and a base-36 digit, e.g. %q", s, NodePrefixes, "%7")
+	}
+	salt, err := codec.FromBase36(strings.ToUpper(s[1:]))
+	if err != nil {
+		return Node{}, fmt.Errorf("invalid node id %q: %w", s, err)
+	}
+	n := Node{Prefix: s[0], Salt: int(salt)}
+	if err := n.validate(); err != nil {
+		return Node{}, fmt.Errorf("invalid node id %q: %w", s, err)
+	}
+	return n, nil
+}
+
+// HostNode derives a node from a fingerprint of this host: its hostname and,
+// where available, the machine id. The same host always gets the same node;
+// different hosts may still collide, so fleets should assign nodes explicitly.
+func HostNode() (Node, error) {
+	host, err := os.Hostname()
+	if err != nil {
+		return Node{}, fmt.Errorf("host fingerprint: %w", err)
+	}
+	h := sha256.New()
+	h.Write([]byte(host))
+	for _, p := range []string{"/etc/machine-id", "/var/lib/dbus/machine-id"} {
+		if b, err := os.ReadFile(p); err == nil {
+			h.Write([]byte{0})
+			h.Write([]byte(strings.TrimSpace(string(b))))
+			break
+		}
+	}
+	v := binary.BigEndian.Uint32(h.Sum(nil)) % uint32(len(NodePrefixes)*36)
+	return Node{Prefix: NodePrefixes[v/36], Salt: int(v % 36)}, nil
+}
+
+// randomNode draws the prefix and salt independently from r.
+func randomNode(r io.Reader) (Node, error) {
+	p, err := randomDigit(r, len(NodePrefixes))
+	if err != nil {
+		return Node{}, err
+	}
+	salt, err := randomDigit(r, 36)
+	if err != nil {
+		return Node{}, err
+	}
+	return Node{Prefix: NodePrefixes[p], Salt: salt}, nil
+}
@@ -0,0 +1,53 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseNode(t *testing.T) {
+	// every node round-trips through String, and the salt digit may be lower case
+	seen := map[string]bool{}
+	for i := 0; i < len(NodePrefixes); i++ {
+		for salt := 0; salt < 36; salt++ {
+			n := Node{Prefix: NodePrefixes[i], Salt: salt}
+			s := n.String()
+			if seen[s] {
+				t.Fatalf("%v renders as %q, like another node", n, s)
+			}
+			seen[s] = true
+			for _, in := range []string{s, strings.ToLower(s)} {
+				got, err := ParseNode(in)
+				if err != nil || got != n {
+					t.Errorf("ParseNode(%q) = %v, %v; want %v", in, got, err, n)
+				}
+			}
+		}
+	}
+	if len(seen) != len(NodePrefixes)*36 {
+		t.Errorf("got %d distinct nodes, want %d", len(seen), len(NodePrefixes)*36)
+	}
+
+	for _, s := range []string{"", "%", "%77", "^7", "~7", "#7", "77", "%#", "%-"} {
+		if n, err := ParseNode(s); err == nil {
+			t.Errorf("ParseNode(%q) = %v, want an error", s, n)
+		}
+	}
+}
+
+func TestHostNode(t *testing.T) {
+	a, err := HostNode()
+	if err != nil {
+		t.Skipf("no host fingerprint: %v", err)
+	}
+	if err := a.validate(); err != nil {
+		t.Errorf("HostNode() = %v: %v", a, err)
+	}
+	b, err := HostNode()
+	if err != nil || b != a {
+		t.Errorf("HostNode() gave %v, then %v, %v", a, b, err)
+	}
+	if _, err := New(testOptions(), WithNode(a)); err != nil {
+		t.Errorf("WithNode(HostNode()): %v", err)
+	}
+}
@@ -0,0 +1,201 @@
+package generator
+
+import (
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ryanl/vizid/internal/codec"
+)
+
+// maxCounterSpace bounds a stored counter: the largest layout's counter
+// space. Generators with different layouts may share one state file.
+var maxCounterSpace = codec.Layout{Counter: codec.MaxCounterDigits}.CounterSpace()
+
+// StateFile persists a generator's counter state (last millisecond and
+// counter) so separate processes on one host continue each other's
+// sequence. Every access holds an advisory lock on the file for the whole
+// read-advance-write cycle.
+type StateFile struct {
+	path string
+}
+
+// NewStateFile returns a StateFile at path. The file and its directory are
+// created on first use.
+func NewStateFile(path string) *StateFile {
+	return &StateFile{path: path}
+}
+
+// Path returns the file's location.
+func (f *StateFile) Path() string { return f.path }
+
+// DefaultStatePath returns $XDG_STATE_HOME/vizid/state, falling back to
+// ~/.local/state/vizid/state.
+func DefaultStatePath() (string, error) {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "vizid", "state"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "state", "vizid", "state"), nil
+}
+
+// acquire opens and locks the file, blocking until no other process holds it.
+func (f *StateFile) acquire() (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(f.path), 0o700); err != nil {
+		return nil, fmt.Errorf("state file: %w", err)
+	}
+	fh, err := os.OpenFile(f.path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("state file: %w", err)
+	}
+	if err := lockFile(fh); err != nil {
+		fh.Close()
+		return nil, fmt.Errorf("state file %s: lock: %w", f.path, err)
+	}
+	return fh, nil
+}
+
+func (f *StateFile) release(fh *os.File) {
+	_ = unlockFile(fh)
+	_ = fh.Close()
+}
+
+// storedState is the file's contents: a line "lastMs counter wallMs peak
+// liveFrom", then a line "at ms next" per millisecond ReserveAt has issued
+// IDs for, holding its next free counter, and "upto ms next" once old ones
+// have been folded into one bound for every millisecond up to ms. A line
+// "end crc" closes the file (see write). wallMs
+// runs ahead of lastMs after ReserveAt, which moves it past the reserved
+// milliseconds; liveFrom is the first millisecond the live counter stamped.
+// Files written before borrowing existed have no wallMs; it is then lastMs.
+// Files without peak take counter for it, and files without liveFrom 0, so
+// peak covers every millisecond before lastMs.
+type storedState struct {
+	lastMs   int64
+	counter  int
+	wallMs   int64
+	peak     int
+	liveFrom int64
+	at       map[int64]int
+	uptoMs   int64
+	uptoNext int
+}
+
+// read returns the stored state; an empty file is the zero state. A file
+// torn by a crash while it was written (no intact end line) is replaced by
+// the backup written just before it; files from before end lines existed
+// are read as they are when there is no backup.
+func (f *StateFile) read(fh *os.File) (storedState, error) {
+	if _, err := fh.Seek(0, io.SeekStart); err != nil {
+		return storedState{}, fmt.Errorf("state file %s: %w", f.path, err)
+	}
+	b, err := io.ReadAll(fh)
+	if err != nil {
+		return storedState{}, fmt.Errorf("state file %s: %w", f.path, err)
+	}
+	if len(strings.TrimSpace(string(b))) == 0 {
+		return storedState{}, nil
+	}
+	st, sealed, err := f.parse(b)
+	if err == nil && sealed {
+		return st, nil
+	}
+	if bak, berr := os.ReadFile(f.backupPath()); berr == nil {
+		if bst, ok, berr := f.parse(bak); berr == nil && ok {
+			return bst, nil
+		}
+	}
+	return st, err
+}
+
+// parse reads the file's contents, reporting whether they end in an intact
+// end line.
+func (f *StateFile) parse(b []byte) (storedState, bool, error) {
+	text := string(b)
+	sealed := false
+	if i := strings.LastIndex(strings.TrimRight(text, "\n"), "\n"); i >= 0 && strings.HasPrefix(text[i+1:], "end ") {
+		var sum uint32
+		if n, _ := fmt.Sscanf(text[i+1:], "end %08x\n", &sum); n != 1 || sum != crc32.ChecksumIEEE(b[:i+1]) {
+			return storedState{}, false, fmt.Errorf("state file %s: checksum mismatch, the file is torn", f.path)
+		}
+		text, sealed = text[:i+1], true
+	}
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	s := strings.TrimSpace(lines[0])
+	st := storedState{at: map[int64]int{}}
+	n, _ := fmt.Sscan(s, &st.lastMs, &st.counter, &st.wallMs, &st.peak, &st.liveFrom)
+	if n == 2 {
+		st.wallMs = st.lastMs
+	}
+	if n < 4 {
+		st.peak = st.counter
+	}
+	if n < 2 || len(strings.Fields(s)) != n || st.counter < 0 || st.counter >= maxCounterSpace ||
+		st.peak < st.counter || st.peak >= maxCounterSpace {
+		return storedState{}, false, fmt.Errorf("state file %s: malformed contents %q", f.path, s)
+	}
+	for _, line := range lines[1:] {
+		var key string
+		var ms int64
+		var next int
+		if n, _ := fmt.Sscanf(line, "%s %d %d", &key, &ms, &next); n != 3 || len(strings.Fields(line)) != 3 || next < 1 || next > maxCounterSpace {
+			return storedState{}, false, fmt.Errorf("state file %s: malformed line %q", f.path, line)
+		}
+		switch key {
+		case "at":
+			st.at[ms] = next
+		case "upto":
+			st.uptoMs, st.uptoNext = ms, next
+		default:
+			return storedState{}, false, fmt.Errorf("state file %s: malformed line %q", f.path, line)
+		}
+	}
+	return st, sealed, nil
+}
+
+// write stores st, closed by an "end" line holding the CRC-32 of the lines
+// before it. The same contents first replace the backup, atomically, and
+// are then written over the file in place, which keeps its lock: a crash
+// part way leaves a torn file and an intact backup for read to fall back to.
+func (f *StateFile) write(fh *os.File, st storedState) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d %d %d %d %d\n", st.lastMs, st.counter, st.wallMs, st.peak, st.liveFrom)
+	if st.uptoNext > 0 {
+		fmt.Fprintf(&b, "upto %d %d\n", st.uptoMs, st.uptoNext)
+	}
+	at := make([]int64, 0, len(st.at))
+	for ms := range st.at {
+		at = append(at, ms)
+	}
+	sort.Slice(at, func(i, j int) bool { return at[i] < at[j] })
+	for _, ms := range at {
+		fmt.Fprintf(&b, "at %d %d\n", ms, st.at[ms])
+	}
+	fmt.Fprintf(&b, "end %08x\n", crc32.ChecksumIEEE([]byte(b.String())))
+	data := []byte(b.String())
+
+	tmp := f.backupPath() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("state file %s: backup: %w", f.path, err)
+	}
+	if err := os.Rename(tmp, f.backupPath()); err != nil {
+		return fmt.Errorf("state file %s: backup: %w", f.path, err)
+	}
+	if _, err := fh.WriteAt(data, 0); err != nil {
+		return fmt.Errorf("state file %s: %w", f.path, err)
+	}
+	if err := fh.Truncate(int64(len(data))); err != nil {
+		return fmt.Errorf("state file %s: %w", f.path, err)
+	}
+	return nil
+}
+
+// backupPath is where write keeps a copy of the latest contents.
+func (f *StateFile) backupPath() string { return f.path + ".bak" }
@@ -0,0 +1,111 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// roundTrip writes st to f and reads it back, each under the file's lock.
+func roundTrip(t *testing.T, f *StateFile, st storedState) storedState {
+	t.Helper()
+	fh, err := f.acquire()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.release(fh)
+	if err := f.write(fh, st); err != nil {
+		t.Fatal(err)
+	}
+	got, err := f.read(fh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+func readState(t *testing.T, f *StateFile) (storedState, error) {
+	t.Helper()
+	fh, err := f.acquire()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.release(fh)
+	return f.read(fh)
+}
+
+func TestStateFileRoundTrip(t *testing.T) {
+	f := NewStateFile(filepath.Join(t.TempDir(), "vizid", "state"))
+	tests := []storedState{
+		{lastMs: 1709208000000, counter: 5, wallMs: 1709208000002, peak: 40, liveFrom: 1709207000000, at: map[int64]int{}},
+		{
+			lastMs: 1709208000000, counter: 5, wallMs: 1709208000000, peak: 5, liveFrom: 1709208000000,
+			at:     map[int64]int{1709200000000: 3, 1709200000001: 1296},
+			uptoMs: 1709100000000, uptoNext: 12,
+		},
+		// a long state replaced by a shorter one leaves no stale lines
+		{lastMs: 1709208000001, counter: 0, wallMs: 1709208000001, peak: 5, at: map[int64]int{}},
+	}
+	for _, st := range tests {
+		if got := roundTrip(t, f, st); !reflect.DeepEqual(got, st) {
+			t.Errorf("got %+v, want %+v", got, st)
+		}
+	}
+}
+
+func TestStateFileTorn(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state")
+	f := NewStateFile(path)
+	want := storedState{lastMs: 1709208000000, counter: 5, wallMs: 1709208000000, peak: 5, at: map[int64]int{1709200000000: 3}}
+	roundTrip(t, f, want)
+	full, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, torn := range [][]byte{
+		full[:len(full)/2],                               // cut off mid-line
+		full[:len(full)-len("end 00000000\n")],           // cut off before the end line
+		append(append([]byte{}, full...), "at 1 2\n"...), // an old longer file's tail
+	} {
+		if err := os.WriteFile(path, torn, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		got, err := readState(t, f)
+		if err != nil {
+			t.Fatalf("%q: %v", torn, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%q: got %+v, want the backup's %+v", torn, got, want)
+		}
+	}
+
+	// without a backup a file failing its checksum is an error, not a state
+	if err := os.Remove(path + ".bak"); err != nil {
+		t.Fatal(err)
+	}
+	bad := append([]byte{}, full...)
+	bad[len(full)-len("3\nend 00000000\n")] = '4'
+	if err := os.WriteFile(path, bad, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if st, err := readState(t, f); err == nil {
+		t.Errorf("got %+v, want an error", st)
+	}
+}
+
+func TestStateFileWithoutEndLine(t *testing.T) {
+	// files written before end lines existed are read as they are
+	path := filepath.Join(t.TempDir(), "state")
+	if err := os.WriteFile(path, []byte("1709208000000 5 1709208000002\nat 1709200000000 3\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readState(t, NewStateFile(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := storedState{lastMs: 1709208000000, counter: 5, wallMs: 1709208000002, peak: 5, at: map[int64]int{1709200000000: 3}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
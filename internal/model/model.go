@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 type Components struct {
 	Year   bool
 	Month  bool
@@ -11,9 +13,35 @@ type Components struct {
 	UUID   bool
 }
 
+// ClockPolicy says what the generator does when the wall clock steps
+// backwards (for example after an NTP adjustment).
+type ClockPolicy string
+
+const (
+	ClockError   ClockPolicy = "error"   // fail the call (the default)
+	ClockWait    ClockPolicy = "wait"    // sleep until the clock catches up
+	ClockLogical ClockPolicy = "logical" // keep counting from the last issued millisecond
+	ClockWarn    ClockPolicy = "warn"    // use the regressed time and report a warning
+)
+
+// ClockPolicies lists the valid ClockPolicy values.
+var ClockPolicies = []ClockPolicy{ClockError, ClockWait, ClockLogical, ClockWarn}
+
+// OverflowPolicy says what the generator does when all 1296 counter values
+// of a millisecond are used.
+type OverflowPolicy string
+
+const (
+	OverflowWait   OverflowPolicy = "wait"   // block until the next millisecond (the default)
+	OverflowBorrow OverflowPolicy = "borrow" // stamp the next millisecond now, running ahead of the clock
+)
+
 type Options struct {
-	Timezone   string
-	Warn       bool
-	Custom     bool
-	Components Components
+	Timezone    string
+	Warn        bool
+	Custom      bool
+	ClockPolicy ClockPolicy    // empty means ClockError
+	Overflow    OverflowPolicy // empty means OverflowWait
+	MaxDrift    time.Duration  // borrow limit; zero means unlimited
+	Components  Components
 }
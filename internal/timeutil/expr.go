@@ -0,0 +1,222 @@
+package timeutil
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseExpr resolves a time expression against loc and the reference time
+// now. Besides the exact forms of ParseInstant it accepts:
+//
+//	now, today, yesterday, tomorrow, noon, midnight
+//	-2h, +30m, -1d12h, -1w         offsets from now (ms s m h d w)
+//	2 hours ago, in 3 days          the same in words
+//	monday, last monday, next fri   weekdays (bare: the latest, today included)
+//	2024-02-29, 20240229            a date
+//
+// and any day form followed by a time of day: "yesterday 15:30",
+// "last monday at 9am", "2024-02-29 15:30:05", or a time alone for today.
+// Day forms without a time mean midnight.
+func ParseExpr(expr string, loc *time.Location, now time.Time) (time.Time, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	if t, err := ParseInstant(expr, loc); err == nil {
+		return t, nil
+	}
+	now = now.In(loc)
+	s := strings.ToLower(strings.Join(strings.Fields(expr), " "))
+	if s == "now" {
+		return now, nil
+	}
+	if offsetExprRe.MatchString(s) {
+		d, err := parseOffset(s[1:])
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid time %q: %w", expr, err)
+		}
+		if s[0] == '-' {
+			d = -d
+		}
+		return now.Add(d), nil
+	}
+	if m := agoRe.FindStringSubmatch(s); m != nil {
+		d, err := wordsOffset(m[1], m[2])
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid time %q: %w", expr, err)
+		}
+		return now.Add(-d), nil
+	}
+	if m := inRe.FindStringSubmatch(s); m != nil {
+		d, err := wordsOffset(m[1], m[2])
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid time %q: %w", expr, err)
+		}
+		return now.Add(d), nil
+	}
+
+	fields := strings.Fields(s)
+	var clock *timeOfDay
+	if n := len(fields); n > 0 {
+		if tod, ok := parseTimeOfDay(fields[n-1]); ok {
+			clock = &tod
+			fields = fields[:n-1]
+			if n := len(fields); n > 0 && fields[n-1] == "at" {
+				fields = fields[:n-1]
+			}
+		}
+	}
+	day, ok := parseDay(strings.Join(fields, " "), now, clock != nil)
+	if !ok {
+		return time.Time{}, fmt.Errorf("invalid time %q: want an exact time (RFC 3339, wire timestamp, ms:<unix ms>), an offset like -2h, \"2 hours ago\", or a day (today, yesterday, last monday, 2024-02-29, 20240229) with an optional time like 15:30", expr)
+	}
+	y, mo, d := day.Date()
+	var tod timeOfDay
+	if clock != nil {
+		tod = *clock
+	}
+	return time.Date(y, mo, d, tod.hour, tod.minute, tod.second, tod.nanos, loc), nil
+}
+
+var (
+	offsetExprRe = regexp.MustCompile(`^[+-](\d+(\.\d+)?(ms|s|m|h|d|w))+$`)
+	offsetPartRe = regexp.MustCompile(`(\d+(?:\.\d+)?)(ms|s|m|h|d|w)`)
+	agoRe        = regexp.MustCompile(`^(\d+(?:\.\d+)?) ?([a-z]+) ago$`)
+	inRe         = regexp.MustCompile(`^in (\d+(?:\.\d+)?) ?([a-z]+)$`)
+	todRe        = regexp.MustCompile(`^(\d{1,2})(?::(\d{2})(?::(\d{2})(?:\.(\d{1,9}))?)?)?(am|pm)?$`)
+	dateRe       = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+)
+
+var unitDurations = map[string]time.Duration{
+	"ms": time.Millisecond, "msec": time.Millisecond, "millisecond": time.Millisecond, "milliseconds": time.Millisecond,
+	"s": time.Second, "sec": time.Second, "secs": time.Second, "second": time.Second, "seconds": time.Second,
+	"m": time.Minute, "min": time.Minute, "mins": time.Minute, "minute": time.Minute, "minutes": time.Minute,
+	"h": time.Hour, "hr": time.Hour, "hrs": time.Hour, "hour": time.Hour, "hours": time.Hour,
+	"d": 24 * time.Hour, "day": 24 * time.Hour, "days": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour, "week": 7 * 24 * time.Hour, "weeks": 7 * 24 * time.Hour,
+}
+
+// parseOffset reads a run of number+unit parts such as "1d12h".
+func parseOffset(s string) (time.Duration, error) {
+	var total time.Duration
+	for _, m := range offsetPartRe.FindAllStringSubmatch(s, -1) {
+		d, err := wordsOffset(m[1], m[2])
+		if err != nil {
+			return 0, err
+		}
+		total += d
+	}
+	return total, nil
+}
+
+func wordsOffset(num, unit string) (time.Duration, error) {
+	u, ok := unitDurations[unit]
+	if !ok {
+		return 0, fmt.Errorf("unknown unit %q", unit)
+	}
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(f * float64(u)), nil
+}
+
+type timeOfDay struct {
+	hour, minute, second, nanos int
+}
+
+// parseTimeOfDay reads 15:30, 15:30:05.250, 9am, 3:30pm, noon and midnight.
+// A bare number is not a time of day.
+func parseTimeOfDay(s string) (timeOfDay, bool) {
+	switch s {
+	case "noon":
+		return timeOfDay{hour: 12}, true
+	case "midnight":
+		return timeOfDay{}, true
+	}
+	m := todRe.FindStringSubmatch(s)
+	if m == nil || (m[2] == "" && m[5] == "") {
+		return timeOfDay{}, false
+	}
+	var t timeOfDay
+	t.hour, _ = strconv.Atoi(m[1])
+	t.minute, _ = strconv.Atoi(m[2])
+	t.second, _ = strconv.Atoi(m[3])
+	if m[4] != "" {
+		t.nanos, _ = strconv.Atoi(m[4] + strings.Repeat("0", 9-len(m[4])))
+	}
+	switch m[5] {
+	case "am", "pm":
+		if t.hour < 1 || t.hour > 12 {
+			return timeOfDay{}, false
+		}
+		t.hour %= 12
+		if m[5] == "pm" {
+			t.hour += 12
+		}
+	}
+	if t.hour > 23 || t.minute > 59 || t.second > 59 {
+		return timeOfDay{}, false
+	}
+	return t, true
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "sun": time.Sunday,
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday, "thurs": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+}
+
+// parseDay resolves a day phrase to a time on that day. An empty phrase is
+// today, but only when a time of day follows it.
+func parseDay(s string, now time.Time, hasTime bool) (time.Time, bool) {
+	switch s {
+	case "":
+		return now, hasTime
+	case "today":
+		return now, true
+	case "yesterday":
+		return now.AddDate(0, 0, -1), true
+	case "tomorrow":
+		return now.AddDate(0, 0, 1), true
+	}
+	if dateRe.MatchString(s) {
+		t, err := time.ParseInLocation("2006-01-02", s, now.Location())
+		return t, err == nil
+	}
+	if len(s) == 8 && dateNumRe.MatchString(s) {
+		t, err := parseDateNum(s, now.Location())
+		return t, err == nil
+	}
+	rel, name, found := strings.Cut(s, " ")
+	if !found {
+		rel, name = "", s
+	}
+	wd, ok := weekdays[name]
+	if !ok {
+		return time.Time{}, false
+	}
+	back := (int(now.Weekday()) - int(wd) + 7) % 7 // days since the latest wd, 0 if today
+	switch rel {
+	case "":
+		return now.AddDate(0, 0, -back), true
+	case "last":
+		if back == 0 {
+			back = 7
+		}
+		return now.AddDate(0, 0, -back), true
+	case "next":
+		ahead := (int(wd) - int(now.Weekday()) + 7) % 7
+		if ahead == 0 {
+			ahead = 7
+		}
+		return now.AddDate(0, 0, ahead), true
+	}
+	return time.Time{}, false
+}
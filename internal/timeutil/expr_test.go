@@ -0,0 +1,94 @@
+package timeutil
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseExpr(t *testing.T) {
+	// a Thursday
+	now := time.Date(2024, 2, 29, 12, 30, 0, 0, time.UTC)
+	day := func(y int, m time.Month, d, hh, mm int) time.Time {
+		return time.Date(y, m, d, hh, mm, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		expr    string
+		want    time.Time
+		wantErr bool
+	}{
+		{expr: "2024-02-29T10:00:00.5Z", want: time.Date(2024, 2, 29, 10, 0, 0, 5e8, time.UTC)},
+		{expr: "2024-02-29 15:30:05", want: time.Date(2024, 2, 29, 15, 30, 5, 0, time.UTC)},
+		{expr: "20240229100000500", want: time.Date(2024, 2, 29, 10, 0, 0, 5e8, time.UTC)},
+		{expr: "20240229100000500-@LO00Y", want: time.Date(2024, 2, 29, 10, 0, 0, 5e8, time.UTC)},
+		{expr: "20240229100000500-%GT0071", want: time.Date(2024, 2, 29, 10, 0, 0, 5e8, time.UTC)},
+		{expr: "ms:1709200800500", want: time.Date(2024, 2, 29, 10, 0, 0, 5e8, time.UTC)},
+		{expr: "@1709200800500", want: time.Date(2024, 2, 29, 10, 0, 0, 5e8, time.UTC)},
+		{expr: "1709200800500", want: time.Date(2024, 2, 29, 10, 0, 0, 5e8, time.UTC)},
+		{expr: "ms:1500", want: time.UnixMilli(1500).UTC()},
+		{expr: "2024", want: day(2024, 1, 1, 0, 0)},
+		{expr: "20240229", want: day(2024, 2, 29, 0, 0)},
+		{expr: "20240229 15:30", want: day(2024, 2, 29, 15, 30)},
+		{expr: "20250229", wantErr: true},
+		{expr: "123", wantErr: true},
+		{expr: "12345", wantErr: true},
+		{expr: "now", want: now},
+		{expr: "-2h", want: now.Add(-2 * time.Hour)},
+		{expr: "+1d12h", want: now.Add(36 * time.Hour)},
+		{expr: "2 hours ago", want: now.Add(-2 * time.Hour)},
+		{expr: "in 3 days", want: now.Add(72 * time.Hour)},
+		{expr: "today", want: day(2024, 2, 29, 0, 0)},
+		{expr: "yesterday 15:30", want: day(2024, 2, 28, 15, 30)},
+		{expr: "tomorrow", want: day(2024, 3, 1, 0, 0)},
+		{expr: "thursday", want: day(2024, 2, 29, 0, 0)},
+		{expr: "last thursday", want: day(2024, 2, 22, 0, 0)},
+		{expr: "next thu", want: day(2024, 3, 7, 0, 0)},
+		{expr: "last monday at 9am", want: day(2024, 2, 26, 9, 0)},
+		{expr: "2024-02-29 3:30pm", want: day(2024, 2, 29, 15, 30)},
+		{expr: "noon", want: day(2024, 2, 29, 12, 0)},
+		{expr: "Yesterday  Noon", want: day(2024, 2, 28, 12, 0)},
+		{expr: "13pm", wantErr: true},
+		{expr: "someday", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := ParseExpr(tt.expr, time.UTC, now)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseExprLocation(t *testing.T) {
+	loc := time.FixedZone("+02:00", 2*3600)
+	now := time.Date(2024, 2, 29, 23, 30, 0, 0, time.UTC) // already Mar 1 in loc
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{expr: "today", want: time.Date(2024, 3, 1, 0, 0, 0, 0, loc)},
+		{expr: "20240229", want: time.Date(2024, 2, 29, 0, 0, 0, 0, loc)},
+		{expr: "20240229100000500", want: time.Date(2024, 2, 29, 10, 0, 0, 5e8, loc)},
+		{expr: "2024-02-29T10:00:00Z", want: time.Date(2024, 2, 29, 10, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := ParseExpr(tt.expr, loc, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
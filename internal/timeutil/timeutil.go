@@ -3,6 +3,9 @@ package timeutil
 import (
 	"fmt"
 	"regexp"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -30,9 +33,93 @@ func LoadLocation(spec string) (*time.Location, error) {
 		off := sign * ((hh * 3600) + (mm * 60))
 		return time.FixedZone(spec, off), nil
 	}
+	if loc, ok := zoneCache.Load(spec); ok {
+		return loc.(*time.Location), nil
+	}
 	loc, err := time.LoadLocation(spec)
 	if err != nil {
 		return nil, fmt.Errorf("invalid timezone %q: %w", spec, err)
 	}
+	zoneCache.Store(spec, loc)
 	return loc, nil
 }
+
+// zoneCache holds IANA zones already loaded; time.LoadLocation reads the
+// zone database on every call.
+var zoneCache sync.Map
+
+// ParseInstant reads an instant written as:
+//   - RFC 3339: 2026-01-30T12:25:20.780Z (without an offset, loc applies)
+//   - an ASCII wire timestamp, YYYYMMDDhhmmssmmm, read as wall time in loc;
+//     a full wire ID (with -PTTCCR) is accepted and its timestamp used
+//   - a date, YYYYMMDD, or a year, YYYY: midnight at its start in loc
+//   - Unix milliseconds: ms:1769775920780 or @1769775920780, or bare with at
+//     least 10 digits, so shorter numbers are not read as instants in 1970
+func ParseInstant(s string, loc *time.Location) (time.Time, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	s = strings.TrimSpace(s)
+	if wireRe.MatchString(s) {
+		return parseWire(s[:17], loc)
+	}
+	if dateNumRe.MatchString(s) {
+		return parseDateNum(s, loc)
+	}
+	if m := unixMsRe.FindStringSubmatch(s); m != nil {
+		ms, err := strconv.ParseInt(m[1]+m[2]+m[3]+m[4], 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid unix milliseconds %q: %w", s, err)
+		}
+		return time.UnixMilli(ms).In(loc), nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05.999999999", "2006-01-02 15:04:05.999999999", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid instant %q: want RFC 3339, a YYYYMMDDhhmmssmmm wire timestamp, a YYYYMMDD date, a YYYY year or unix milliseconds (ms:1769775920780)", s)
+}
+
+var (
+	wireRe    = regexp.MustCompile(`^\d{17}(-[!-~]{3,10})?$`)
+	dateNumRe = regexp.MustCompile(`^(\d{4}|\d{8})$`)
+	// unixMsRe matches ms:N and @N, and bare N of 10 to 16 digits
+	unixMsRe = regexp.MustCompile(`^(?:ms:|@)(-?)(\d{1,16})$|^(-?)(\d{10,16})$`)
+)
+
+func parseWire(s string, loc *time.Location) (time.Time, error) {
+	num := func(i, j int) int {
+		n, _ := strconv.Atoi(s[i:j])
+		return n
+	}
+	year, month, day := num(0, 4), num(4, 6), num(6, 8)
+	hour, minute, second, ms := num(8, 10), num(10, 12), num(12, 14), num(14, 17)
+	if month < 1 || month > 12 || day < 1 || day > DaysIn(year, month) || hour > 23 || minute > 59 || second > 59 {
+		return time.Time{}, fmt.Errorf("invalid wire timestamp %q", s)
+	}
+	return time.Date(year, time.Month(month), day, hour, minute, second, ms*int(time.Millisecond), loc), nil
+}
+
+// parseDateNum reads YYYY or YYYYMMDD as midnight at its start in loc.
+func parseDateNum(s string, loc *time.Location) (time.Time, error) {
+	year, _ := strconv.Atoi(s[:4])
+	month, day := 1, 1
+	if len(s) == 8 {
+		month, _ = strconv.Atoi(s[4:6])
+		day, _ = strconv.Atoi(s[6:8])
+		if month < 1 || month > 12 || day < 1 || day > DaysIn(year, month) {
+			return time.Time{}, fmt.Errorf("invalid date %q", s)
+		}
+	}
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, loc), nil
+}
+
+// DaysIn returns the number of days in month of year in the proleptic
+// Gregorian calendar.
+func DaysIn(year, month int) int {
+	return time.Date(year, time.Month(month)+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
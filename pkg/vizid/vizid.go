@@ -0,0 +1,469 @@
+// Package vizid is the public Go API for VIZIDs: visual, sortable
+// timestamp + UUID identifiers designed for filenames.
+//
+// An ID has two reversible forms:
+//
+//	ASCII (wire):  YYYYMMDDhhmmssmmm-PTTCCR
+//	VIZ (glyphs):  <12 glyphs>-<6 glyphs>
+//
+// Generate mints new IDs, Parse reads either form, Encode and Decode convert
+// between them, and alphabets select the glyph set of the VIZ form.
+//
+//	id, _, err := vizid.Generate(vizid.DefaultOptions(), nil)
+//	if err != nil { ... }
+//	fmt.Println(id.VIZ(), id.ASCII(), id.Time())
+//
+//	id, err = vizid.Parse("20260130122520780-@LO00Y")
+//
+// The package follows semantic versioning as recorded in APIVersion; the
+// packages under internal/ are implementation details and may change freely.
+package vizid
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/ryanl/vizid/internal/codec"
+	"github.com/ryanl/vizid/internal/generator"
+	"github.com/ryanl/vizid/internal/model"
+	"github.com/ryanl/vizid/internal/timeutil"
+)
+
+// APIVersion is the version of this package's API. Version 2 reserves the
+// prefixes DescriptorPrefix and ContentPrefix, so some 1.x IDs using them
+// as node prefixes read differently; see docs/tdd.md.
+const APIVersion = "2.0.0"
+
+type (
+	// ID is a parsed or generated VIZID. Its accessors (Time, Prefix,
+	// TimeMix, Counter, Salt, ASCII, VIZ, ...) are the only supported way
+	// to read the layout.
+	ID = codec.ID
+
+	// Timestamp holds the calendar fields of an ID.
+	Timestamp = codec.Timestamp
+
+	// UUID holds the PTTCCR fields of an ID.
+	UUID = codec.UUID
+
+	// Alphabet is a named, versioned core-36 + prefix glyph set.
+	Alphabet = codec.Alphabet
+
+	// GlyphSpec describes a user-defined alphabet for NewCustomAlphabet.
+	GlyphSpec = codec.GlyphSpec
+
+	// DecodeError is the error Parse, Decode and friends return for a
+	// malformed ID: the field, the rune offset and glyph at fault, and why.
+	DecodeError = codec.DecodeError
+
+	// LintReport is the result of auditing an alphabet with Lint.
+	LintReport = codec.LintReport
+
+	// Options control generation: timezone, sort warnings and components.
+	Options = model.Options
+
+	// Components selects which timestamp fields and the UUID are emitted.
+	Components = model.Components
+
+	// ClockPolicy says what a Generator does when the wall clock steps
+	// backwards.
+	ClockPolicy = model.ClockPolicy
+
+	// Generator mints IDs with its own monotonic counter state. Build one
+	// with NewGenerator.
+	Generator = generator.Generator
+
+	// Block is a run of consecutive IDs reserved by Generator.Reserve or
+	// GenerateN, rendered on demand with Block.ID.
+	Block = generator.Block
+
+	// GeneratorOption configures a Generator.
+	GeneratorOption = generator.Option
+
+	// OverflowPolicy says what a Generator does when a millisecond's
+	// counter values run out.
+	OverflowPolicy = model.OverflowPolicy
+
+	// DriftStats reports how far a borrowing Generator runs ahead of the
+	// wall clock.
+	DriftStats = generator.DriftStats
+
+	// Node is a Generator's identity: UUID prefix and salt digit.
+	Node = generator.Node
+
+	// Clock is a Generator's time source.
+	Clock = generator.Clock
+
+	// ContextSleeper is implemented by clocks whose Sleep can be cut short
+	// when a context is done.
+	ContextSleeper = generator.ContextSleeper
+
+	// SystemClock is the real wall clock, the default Clock.
+	SystemClock = generator.SystemClock
+
+	// FakeClock is a deterministic Clock for tests.
+	FakeClock = generator.FakeClock
+
+	// Mixer produces TT, the time-mix of an ID.
+	Mixer = generator.Mixer
+
+	// MixerID identifies a Mixer; ID.Mixer reports the one an ID used.
+	MixerID = codec.MixerID
+
+	// V1Mixer is the original, unkeyed time-mix, the default Mixer.
+	V1Mixer = generator.V1Mixer
+
+	// KeyedMixer hashes the full timestamp and node with a secret.
+	KeyedMixer = generator.KeyedMixer
+
+	// Layout is the shape of an extended UUID: time-mix, counter digits
+	// and random digits.
+	Layout = codec.Layout
+)
+
+// ClassicLayout is the layout of the classic PTTCCR UUID, the default.
+var ClassicLayout = codec.ClassicLayout
+
+// Layout and UUID width limits.
+const (
+	MaxCounterDigits = codec.MaxCounterDigits
+	MaxRandomDigits  = codec.MaxRandomDigits
+	MinUUIDWidth     = codec.MinUUIDWidth // glyphs, prefix included
+	MaxUUIDWidth     = codec.MaxUUIDWidth
+)
+
+// ParseLayout reads a layout such as "classic", "tt+c2+r1" or "c3".
+func ParseLayout(s string) (Layout, error) { return codec.ParseLayout(s) }
+
+// Time-mix functions.
+const (
+	MixerV1    = codec.MixerV1    // fixed mix of the ms within the minute (the default)
+	MixerKeyed = codec.MixerKeyed // HMAC of the full timestamp and node
+)
+
+// Clock regression policies.
+const (
+	ClockError   = model.ClockError   // fail generation (the default)
+	ClockWait    = model.ClockWait    // sleep until the clock catches up
+	ClockLogical = model.ClockLogical // keep counting from the last issued millisecond
+	ClockWarn    = model.ClockWarn    // use the regressed time and report a warning
+)
+
+// Counter overflow policies.
+const (
+	OverflowWait   = model.OverflowWait   // block until the next millisecond (the default)
+	OverflowBorrow = model.OverflowBorrow // stamp the next millisecond without waiting
+)
+
+// LeapSecondPolicy says how EncodeWith reads second 60.
+type LeapSecondPolicy = codec.LeapSecondPolicy
+
+// Leap second policies.
+const (
+	LeapReject = codec.LeapReject // refuse second 60 (the default)
+	LeapClamp  = codec.LeapClamp  // read it as hh:mm:59.999
+	LeapCarry  = codec.LeapCarry  // carry it into the next minute
+)
+
+// ParseLeapSecondPolicy checks a policy name; empty means LeapReject.
+func ParseLeapSecondPolicy(s string) (LeapSecondPolicy, error) {
+	return codec.ParseLeapSecondPolicy(s)
+}
+
+// PrefixSet is the ASCII UUID prefix set, in byte order.
+const PrefixSet = codec.PrefixSet
+
+// DescriptorPrefix is the prefix of extended ^D... UUIDs, whose digit D
+// records the layout and mixer. It is reserved like ContentPrefix.
+const DescriptorPrefix = codec.DescriptorPrefix
+
+// ContentPrefix is the prefix of content-derived IDs (see FromContent). It
+// is reserved: generator nodes use the other prefixes, NodePrefixes.
+const ContentPrefix = codec.ContentPrefix
+
+// NodePrefixes are the prefixes a generator node may use.
+const NodePrefixes = generator.NodePrefixes
+
+// Built-in alphabets.
+var (
+	DefaultAlphabet = codec.Default // geometric@2
+	LegacyAlphabet  = codec.Legacy  // geometric@1, not sort-safe
+	Faces           = codec.Faces
+	Animals         = codec.Animals
+	Weather         = codec.Weather
+	Runes           = codec.Runes
+	Chess           = codec.Chess
+)
+
+// AllComponents enables every timestamp field and the UUID.
+func AllComponents() Components {
+	return Components{Year: true, Month: true, Day: true, Hour: true, Minute: true, Second: true, Ms: true, UUID: true}
+}
+
+// DefaultOptions returns the CLI defaults: UTC, warnings on, all components,
+// and the error clock policy.
+func DefaultOptions() Options {
+	return Options{Timezone: "UTC", Warn: true, ClockPolicy: ClockError, Components: AllComponents()}
+}
+
+// Generate mints a new ID rendered with alphabet a (nil means
+// DefaultAlphabet), using a process-wide counter and node. The string holds
+// warnings ("; "-separated): a clock regression under ClockWarn, and a
+// sort-order warning when opts.Warn is set and the components could break
+// chronological sorting.
+func Generate(opts Options, a *Alphabet) (ID, string, error) {
+	return generator.GenerateID(opts, a)
+}
+
+// GenerateWith is Generate with extra generator options, for example
+// WithStateFile or WithNode; the process-wide counter still applies.
+func GenerateWith(opts Options, a *Alphabet, options ...GeneratorOption) (ID, string, error) {
+	return generator.GenerateID(opts, a, options...)
+}
+
+// GenerateN reserves n strictly increasing IDs from the process-wide
+// counter under a single lock, spilling into the following milliseconds when
+// one millisecond's 1296 counter values run out.
+//
+//	b, _, err := vizid.GenerateN(ctx, vizid.DefaultOptions(), nil, 5000)
+//	for i := 0; i < b.Len(); i++ {
+//		id, _ := b.ID(i)
+//		fmt.Println(id.ASCII())
+//	}
+func GenerateN(ctx context.Context, opts Options, a *Alphabet, n int, options ...GeneratorOption) (Block, string, error) {
+	return generator.GenerateN(ctx, opts, a, n, options...)
+}
+
+// GenerateAt reserves n strictly increasing IDs for the instant t instead of
+// the current time, for backfilling historical records. Each millisecond
+// keeps its own process-wide counter, so repeated calls for one instant get
+// distinct, ordered IDs. t must not be in the future or at or after the
+// first live ID of the process. With WithStateFile the counters are kept in
+// the file instead, so separate processes continue them.
+func GenerateAt(ctx context.Context, opts Options, a *Alphabet, t time.Time, n int, options ...GeneratorOption) (Block, string, error) {
+	return generator.GenerateAt(ctx, opts, a, t, n, options...)
+}
+
+// FromContent builds the content-derived ID of content: the timestamp of t,
+// then ContentPrefix, a 5-digit hash of namespace and content as TTCCR and
+// the marker digit C. The same inputs always give the same ID; ID.IsContent
+// tells such IDs apart from generated ones and 1.x IDs with prefix
+// ContentPrefix.
+func FromContent(opts Options, a *Alphabet, t time.Time, namespace string, content io.Reader) (ID, error) {
+	return generator.FromContent(opts, a, t, namespace, content)
+}
+
+// ContentHash returns the TTCCR value (0..36^5-1) FromContent derives from
+// namespace and content.
+func ContentHash(namespace string, content io.Reader) (int, error) {
+	return generator.ContentHash(namespace, content)
+}
+
+// ParseInstant reads an instant as RFC 3339, an ASCII wire timestamp
+// (YYYYMMDDhhmmssmmm, wall time in loc), a YYYYMMDD date or YYYY year
+// (midnight in loc), or Unix milliseconds (ms:N or @N, or N of at least 10
+// digits). A nil loc means UTC.
+func ParseInstant(s string, loc *time.Location) (time.Time, error) {
+	return timeutil.ParseInstant(s, loc)
+}
+
+// ParseTime resolves a time expression in loc relative to now: the exact
+// forms of ParseInstant, offsets (-2h, "3 days ago"), and days with an
+// optional time of day ("yesterday 15:30", "last monday", "2024-02-29 9am").
+// A nil loc means UTC.
+func ParseTime(expr string, loc *time.Location, now time.Time) (time.Time, error) {
+	return timeutil.ParseExpr(expr, loc, now)
+}
+
+// LoadLocation resolves a timezone as the CLI's --timezone flag does: an
+// IANA name, a UTC offset like +02:00, or UTC.
+func LoadLocation(spec string) (*time.Location, error) {
+	return timeutil.LoadLocation(spec)
+}
+
+// GenerateContext is GenerateWith returning ctx.Err() if ctx is done while
+// waiting for the clock (counter overflow, or a regressed clock under
+// ClockWait).
+func GenerateContext(ctx context.Context, opts Options, a *Alphabet, options ...GeneratorOption) (ID, string, error) {
+	return generator.GenerateContext(ctx, opts, a, options...)
+}
+
+// NewGenerator builds a Generator. Without options it uses the system clock,
+// crypto/rand entropy, a random prefix and salt, and DefaultAlphabet.
+//
+//	clk := vizid.NewFakeClock(time.Date(2026, 1, 30, 12, 25, 20, 0, time.UTC))
+//	g, err := vizid.NewGenerator(vizid.DefaultOptions(), vizid.WithClock(clk), vizid.WithNode(vizid.Node{Prefix: '%', Salt: 7}))
+func NewGenerator(opts Options, options ...GeneratorOption) (*Generator, error) {
+	return generator.New(opts, options...)
+}
+
+// WithClock sets a Generator's time source.
+func WithClock(c Clock) GeneratorOption { return generator.WithClock(c) }
+
+// WithEntropy sets a Generator's randomness source.
+func WithEntropy(r io.Reader) GeneratorOption { return generator.WithEntropy(r) }
+
+// WithSalt fixes a Generator's salt digit (0..35).
+func WithSalt(salt int) GeneratorOption { return generator.WithSalt(salt) }
+
+// WithPrefix fixes a Generator's UUID prefix (one of NodePrefixes).
+func WithPrefix(p byte) GeneratorOption { return generator.WithPrefix(p) }
+
+// WithNode fixes a Generator's prefix and salt together.
+func WithNode(n Node) GeneratorOption { return generator.WithNode(n) }
+
+// ParseNode reads a node id: a prefix character and a base-36 salt digit,
+// e.g. "%7".
+func ParseNode(s string) (Node, error) { return generator.ParseNode(s) }
+
+// HostNode derives a stable node from a fingerprint of this host.
+func HostNode() (Node, error) { return generator.HostNode() }
+
+// WithStateFile persists a Generator's counter state in the file at path,
+// locked for each ID, so processes sharing the file stay strictly monotonic.
+func WithStateFile(path string) GeneratorOption { return generator.WithStateFile(path) }
+
+// DefaultStatePath returns the CLI's state file location:
+// $XDG_STATE_HOME/vizid/state, or ~/.local/state/vizid/state.
+func DefaultStatePath() (string, error) { return generator.DefaultStatePath() }
+
+// MaxShards is the most shards WithShards accepts.
+const MaxShards = generator.MaxShards
+
+// WithShards splits a Generator's counter into n lock-independent shards so
+// concurrent callers scale across cores. Shards interleave their counter
+// values, so IDs stay unique and time-ordered; each shard gets about 1296/n
+// values per millisecond. Not combinable with WithStateFile.
+func WithShards(n int) GeneratorOption { return generator.WithShards(n) }
+
+// WithAlphabet sets the alphabet a Generator renders IDs with.
+func WithAlphabet(a *Alphabet) GeneratorOption { return generator.WithAlphabet(a) }
+
+// WithMixer sets the function that produces TT (default V1Mixer). Other
+// mixers add a digit recording the mixer to classic UUIDs, PTTCCRM.
+func WithMixer(m Mixer) GeneratorOption { return generator.WithMixer(m) }
+
+// WithLayout sets the UUID layout (default ClassicLayout); other layouts
+// produce extended UUIDs.
+func WithLayout(l Layout) GeneratorOption { return generator.WithLayout(l) }
+
+// NewKeyedMixer returns a KeyedMixer keyed with secret (not empty).
+func NewKeyedMixer(secret []byte) (*KeyedMixer, error) { return generator.NewKeyedMixer(secret) }
+
+// ParseMixer returns the mixer named "v1" (or empty) or "keyed" (with
+// secret).
+func ParseMixer(name string, secret []byte) (Mixer, error) { return generator.ParseMixer(name, secret) }
+
+// NewFakeClock returns a FakeClock reading t.
+func NewFakeClock(t time.Time) *FakeClock { return generator.NewFakeClock(t) }
+
+// New builds an ID from its fields. uuid may be nil for a timestamp-only ID.
+// A nil alphabet means DefaultAlphabet; a nil location means UTC.
+func New(ts Timestamp, uuid *UUID, a *Alphabet, loc *time.Location) (ID, error) {
+	return codec.NewID(ts, uuid, a, loc)
+}
+
+// Parse reads an ID in ASCII or VIZ form, detecting the alphabet of VIZ input.
+func Parse(s string) (ID, error) {
+	return codec.Parse(s)
+}
+
+// ParseWith is Parse trying alphabet a first for VIZ input; parsed IDs render
+// with the alphabet they were read in (a, for ASCII input).
+func ParseWith(s string, a *Alphabet) (ID, error) {
+	return codec.ParseWith(s, a)
+}
+
+// ParseASCII parses an ASCII wire ID, handling second 60 per leap; the ID
+// renders with alphabet a (nil means DefaultAlphabet).
+func ParseASCII(ascii string, a *Alphabet, leap LeapSecondPolicy) (ID, error) {
+	return codec.ParseASCII(ascii, a, leap)
+}
+
+// ParseLenient is ParseWith for IDs pasted from chat apps, PDFs or emoji
+// fonts: it normalizes Unicode, drops invisible characters such as U+FE0F and
+// reads known lookalikes (⬥ for ◆, – for -) as the canonical glyphs. Second
+// 60 in ASCII input is handled per leap. The notes say what was changed. A
+// glyph still unknown fails with a *DecodeError whose Suggest lists likely
+// glyphs of the same visual family.
+func ParseLenient(s string, a *Alphabet, leap LeapSecondPolicy) (ID, []string, error) {
+	return codec.ParseLenient(s, a, leap)
+}
+
+// Normalize returns s as ParseLenient sees it before mapping lookalikes,
+// with notes on what was changed; a lenient DecodeError's Index points into it.
+func Normalize(s string) (string, []string) {
+	return codec.Normalize(s)
+}
+
+// Encode converts an ASCII wire ID to VIZ form in alphabet a (nil means DefaultAlphabet).
+// The date must exist in the Gregorian calendar; leap seconds are refused.
+func Encode(ascii string, a *Alphabet) (string, error) {
+	return codec.EncodeASCIIToVIZ(ascii, a)
+}
+
+// EncodeWith is Encode with a policy for leap seconds (second 60).
+func EncodeWith(ascii string, a *Alphabet, leap LeapSecondPolicy) (string, error) {
+	return codec.EncodeASCIIToVIZWith(ascii, a, leap)
+}
+
+// Decode converts a VIZ ID in alphabet a (nil means DefaultAlphabet) to ASCII.
+func Decode(viz string, a *Alphabet) (string, error) {
+	return codec.DecodeVIZToASCII(viz, a)
+}
+
+// DecodeAny converts a VIZ ID to ASCII, detecting its alphabet; prefer is tried first.
+func DecodeAny(viz string, prefer *Alphabet) (string, *Alphabet, error) {
+	return codec.DecodeAnyVIZToASCII(viz, prefer)
+}
+
+// Reencode converts a VIZ ID from one alphabet to another.
+func Reencode(viz string, from, to *Alphabet) (string, error) {
+	return codec.Reencode(viz, from, to)
+}
+
+// LookupAlphabet returns a registered alphabet by "name" (highest version)
+// or "name@version".
+func LookupAlphabet(spec string) (*Alphabet, error) {
+	return codec.Lookup(spec)
+}
+
+// Alphabets returns every registered alphabet, sorted by name then version.
+func Alphabets() []*Alphabet {
+	return codec.Alphabets()
+}
+
+// NewAlphabet builds an alphabet from a 36-glyph core table and a prefix
+// table keyed by the characters of PrefixSet.
+func NewAlphabet(name string, version int, core []rune, prefix map[byte]rune) (*Alphabet, error) {
+	return codec.NewAlphabet(name, version, core, prefix)
+}
+
+// NewCustomAlphabet validates a user-defined glyph set and builds an
+// alphabet from it; the bool reports whether the tables were re-sorted.
+func NewCustomAlphabet(spec GlyphSpec) (*Alphabet, bool, error) {
+	return codec.NewCustomAlphabet(spec)
+}
+
+// RegisterAlphabet makes an alphabet available to LookupAlphabet and to
+// alphabet detection in Parse and DecodeAny.
+func RegisterAlphabet(a *Alphabet) error {
+	return codec.Register(a)
+}
+
+// VerifyOrdered reports whether an alphabet's tables preserve sort order.
+func VerifyOrdered(a *Alphabet) error {
+	return codec.VerifyOrdered(a.Core(), a.Prefix())
+}
+
+// Lint audits an alphabet against the rules in docs/requirements.md.
+func Lint(a *Alphabet) LintReport {
+	return codec.LintAlphabet(a)
+}
+
+// LintTables audits raw core and prefix tables (prefix in PrefixSet order),
+// for glyph sets that do not load as an Alphabet.
+func LintTables(name string, core, prefix []rune) LintReport {
+	return codec.Lint(name, core, prefix)
+}